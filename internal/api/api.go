@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"strings"
+)
+
+// CharacterAPI is what a player needs to act on its character
+type CharacterAPI interface {
+	GetCharacterCharactersNameGetWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.GetCharacterCharactersNameGetResponse, error)
+	CreateCharacterCharactersCreatePostWithResponse(ctx context.Context, body client.CreateCharacterCharactersCreatePostJSONRequestBody, reqEditors ...client.RequestEditorFn) (*client.CreateCharacterCharactersCreatePostResponse, error)
+	ActionMoveMyNameActionMovePostWithResponse(ctx context.Context, name string, body client.ActionMoveMyNameActionMovePostJSONRequestBody, reqEditors ...client.RequestEditorFn) (*client.ActionMoveMyNameActionMovePostResponse, error)
+	ActionGatheringMyNameActionGatheringPostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionGatheringMyNameActionGatheringPostResponse, error)
+	ActionFightMyNameActionFightPostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionFightMyNameActionFightPostResponse, error)
+	ActionDepositBankMyNameActionBankDepositPostWithResponse(ctx context.Context, name string, body client.ActionDepositBankMyNameActionBankDepositPostJSONRequestBody, reqEditors ...client.RequestEditorFn) (*client.ActionDepositBankMyNameActionBankDepositPostResponse, error)
+	ActionWithdrawBankMyNameActionBankWithdrawPostWithResponse(ctx context.Context, name string, body client.ActionWithdrawBankMyNameActionBankWithdrawPostJSONRequestBody, reqEditors ...client.RequestEditorFn) (*client.ActionWithdrawBankMyNameActionBankWithdrawPostResponse, error)
+	ActionAcceptNewTaskMyNameActionTaskNewPostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionAcceptNewTaskMyNameActionTaskNewPostResponse, error)
+	ActionCompleteTaskMyNameActionTaskCompletePostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionCompleteTaskMyNameActionTaskCompletePostResponse, error)
+	ActionTaskCancelMyNameActionTaskCancelPostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionTaskCancelMyNameActionTaskCancelPostResponse, error)
+	ActionTaskExchangeMyNameActionTaskExchangePostWithResponse(ctx context.Context, name string, reqEditors ...client.RequestEditorFn) (*client.ActionTaskExchangeMyNameActionTaskExchangePostResponse, error)
+}
+
+// WorldAPI is what the collector needs to load the map, monsters, resources, prices, events and the bank
+type WorldAPI interface {
+	GetAllMapsMapsGetWithResponse(ctx context.Context, params *client.GetAllMapsMapsGetParams, reqEditors ...client.RequestEditorFn) (*client.GetAllMapsMapsGetResponse, error)
+	GetAllMonstersMonstersGetWithResponse(ctx context.Context, params *client.GetAllMonstersMonstersGetParams, reqEditors ...client.RequestEditorFn) (*client.GetAllMonstersMonstersGetResponse, error)
+	GetAllResourcesResourcesGetWithResponse(ctx context.Context, params *client.GetAllResourcesResourcesGetParams, reqEditors ...client.RequestEditorFn) (*client.GetAllResourcesResourcesGetResponse, error)
+	GetAllGeItemsGeGetWithResponse(ctx context.Context, params *client.GetAllGeItemsGeGetParams, reqEditors ...client.RequestEditorFn) (*client.GetAllGeItemsGeGetResponse, error)
+	GetAllEventsEventsGetWithResponse(ctx context.Context, params *client.GetAllEventsEventsGetParams, reqEditors ...client.RequestEditorFn) (*client.GetAllEventsEventsGetResponse, error)
+	GetBankDetailsMyBankGetWithResponse(ctx context.Context, reqEditors ...client.RequestEditorFn) (*client.GetBankDetailsMyBankGetResponse, error)
+	GetBankItemsMyBankItemsGetWithResponse(ctx context.Context, params *client.GetBankItemsMyBankItemsGetParams, reqEditors ...client.RequestEditorFn) (*client.GetBankItemsMyBankItemsGetResponse, error)
+}
+
+// GameAPI covers every call the engine makes, *client.ClientWithResponses implements it
+type GameAPI interface {
+	CharacterAPI
+	WorldAPI
+}
+
+var _ GameAPI = (*client.ClientWithResponses)(nil)
+
+// Character returns the character a request path acts for, e.g. alice for /my/alice/action/move, empty for world and account data
+func Character(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "my" && parts[2] == "action" {
+		return parts[1]
+	}
+	if len(parts) == 2 && parts[0] == "characters" && parts[1] != "create" && parts[1] != "delete" {
+		return parts[1]
+	}
+	return ""
+}
@@ -9,6 +9,8 @@ type Action int
 
 const (
 	PlayerStartedCode = -1
+	// ReconciledCode is an action that failed and did not go through, the player re-read its character and the engine plans again
+	ReconciledCode = -2
 )
 
 type Player interface {
@@ -20,6 +22,8 @@ type Player interface {
 	AcceptNewTask(tile models.MapTile) int
 	CompleteTask(tile models.MapTile) (*client.TaskRewardSchema, int)
 	ExchangeTaskCoins(tile models.MapTile) (*client.TaskRewardSchema, int)
+	CancelTask(tile models.MapTile) int
+	WithdrawItem(tile models.MapTile, code string, qty int) int
 }
 
 type StopStepFn func(p Player) bool
@@ -32,11 +36,17 @@ type Command struct {
 type Step interface {
 	Stop(p Player) bool
 	Execute(p Player) (int, error)
+	String() string
+	Activity() string
+	Location() (models.MapTile, bool)
 }
 
 type Stepper struct {
+	Name      string
 	StopFn    StopStepFn
 	ExecuteFn ExecuteStepFn
+	activity  string
+	location  *models.MapTile
 }
 
 func (s *Stepper) Execute(p Player) (int, error) {
@@ -47,8 +57,23 @@ func (s *Stepper) Stop(p Player) bool {
 	return s.StopFn(p)
 }
 
-type CommandResponse struct {
-	Name  string
-	Error error
-	Code  int
+// String describes the step for status reporting
+func (s *Stepper) String() string {
+	return s.Name
+}
+
+// Activity identifies what the step does without the quantity, e.g. "gather ash_tree", for grouping results
+func (s *Stepper) Activity() string {
+	if s.activity == "" {
+		return s.Name
+	}
+	return s.activity
+}
+
+// Location is the tile the step is performed on, false when it has none
+func (s *Stepper) Location() (models.MapTile, bool) {
+	if s.location == nil {
+		return models.MapTile{}, false
+	}
+	return *s.location, true
 }
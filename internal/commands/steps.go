@@ -3,17 +3,40 @@ package commands
 import (
 	"artifactsmmo/internal/models"
 	"fmt"
+	"github.com/sagikazarmark/slog-shim"
 	"net/http"
+	"strings"
 )
 
+func GatherActivity(resource string) string {
+	return fmt.Sprintf("gather %s", resource)
+}
+
+func FightActivity(monster string) string {
+	return fmt.Sprintf("fight %s", monster)
+}
+
+// IsActivity tells gathering and fighting from the steps that support them, e.g. deposits and tasks
+func IsActivity(activity string) bool {
+	return strings.HasPrefix(activity, "gather ") || strings.HasPrefix(activity, "fight ")
+}
+
 func NewGatherStep(qty int, tile models.MapTile) Step {
-	g := struct{ *Stepper }{
-		Stepper: &Stepper{},
+	g := struct {
+		count int
+		*Stepper
+	}{
+		count:   0,
+		Stepper: &Stepper{Name: fmt.Sprintf("gather %d %s", qty, tile.Code), activity: GatherActivity(tile.Code), location: &tile},
 	}
-	g.StopFn = func(p Player) bool { return p.CheckInventory(tile.Code) >= qty }
+	//the tile code is the resource, not the item it drops, so count gathers instead of checking the inventory
+	g.StopFn = func(p Player) bool { return g.count >= qty }
 	g.ExecuteFn = func(p Player) (int, error) {
 		//todo: it would be great if we could deposit when gather returns a 497
 		code := p.Gather(tile)
+		if code == http.StatusOK {
+			g.count += 1
+		}
 		return code, nil
 	}
 
@@ -26,13 +49,13 @@ func NewFightStep(qty int, tile models.MapTile) Step {
 		*Stepper
 	}{
 		count:   0,
-		Stepper: &Stepper{},
+		Stepper: &Stepper{Name: fmt.Sprintf("fight %d %s", qty, tile.Code), activity: FightActivity(tile.Code), location: &tile},
 	}
 	f.StopFn = func(p Player) bool { return f.count >= qty }
 	f.ExecuteFn = func(p Player) (int, error) {
 		win, code := p.Fight(tile)
 		if win {
-			fmt.Printf("won, increasing count from %d to %d\n", qty, f.count)
+			slog.Debug("fight won", "monster", tile.Code, "count", f.count+1, "qty", qty)
 			f.count += 1
 		}
 
@@ -46,7 +69,7 @@ func NewAcceptTaskStep(tile models.MapTile) Step {
 	s := struct {
 		*Stepper
 	}{
-		Stepper: &Stepper{},
+		Stepper: &Stepper{Name: "accept task", location: &tile},
 	}
 
 	s.StopFn = func(p Player) bool { return true }
@@ -67,7 +90,7 @@ func NewCompleteTaskStep(tile models.MapTile) Step {
 	s := struct {
 		*Stepper
 	}{
-		Stepper: &Stepper{},
+		Stepper: &Stepper{Name: "complete task", location: &tile},
 	}
 
 	s.StopFn = func(p Player) bool { return true }
@@ -86,7 +109,7 @@ func NewDepositInventoryStep(tile models.MapTile) Step {
 	s := struct {
 		*Stepper
 	}{
-		Stepper: &Stepper{},
+		Stepper: &Stepper{Name: "deposit inventory", location: &tile},
 	}
 	s.StopFn = func(p Player) bool { return true }
 	s.ExecuteFn = func(p Player) (int, error) {
@@ -99,3 +122,101 @@ func NewDepositInventoryStep(tile models.MapTile) Step {
 
 	return s
 }
+
+func NewCancelTaskStep(tile models.MapTile) Step {
+	s := struct {
+		*Stepper
+	}{
+		Stepper: &Stepper{Name: "cancel task", location: &tile},
+	}
+
+	s.StopFn = func(p Player) bool { return true }
+	s.ExecuteFn = func(p Player) (int, error) {
+		code := p.CancelTask(tile)
+		if code != http.StatusOK {
+			return code, fmt.Errorf("cancel task failed with code %d", code)
+		}
+		return code, nil
+	}
+
+	return s
+}
+
+func NewWithdrawStep(code string, qty int, tile models.MapTile) Step {
+	s := struct {
+		*Stepper
+	}{
+		Stepper: &Stepper{Name: fmt.Sprintf("withdraw %d %s", qty, code), location: &tile},
+	}
+	s.StopFn = func(p Player) bool { return true }
+	s.ExecuteFn = func(p Player) (int, error) {
+		c := p.WithdrawItem(tile, code, qty)
+		if c != http.StatusOK {
+			return c, fmt.Errorf("withdraw %s failed with code %d", code, c)
+		}
+		return c, nil
+	}
+
+	return s
+}
+
+func NewExchangeTaskCoinsStep(tile models.MapTile) Step {
+	s := struct {
+		*Stepper
+	}{
+		Stepper: &Stepper{Name: "exchange task coins", location: &tile},
+	}
+
+	s.StopFn = func(p Player) bool { return true }
+	s.ExecuteFn = func(p Player) (int, error) {
+		_, code := p.ExchangeTaskCoins(tile)
+		if code != http.StatusOK {
+			return code, fmt.Errorf("exchange task coins failed with code %d", code)
+		}
+		return code, nil
+	}
+
+	return s
+}
+
+// NewSequenceStep runs the steps one after the other, e.g. a route planned with world.PlanRoute
+func NewSequenceStep(steps ...Step) Step {
+	names := make([]string, 0, len(steps))
+	for _, st := range steps {
+		names = append(names, st.String())
+	}
+
+	s := struct {
+		current int
+		*Stepper
+	}{
+		current: 0,
+		Stepper: &Stepper{Name: strings.Join(names, ", then ")},
+	}
+	if len(steps) > 0 {
+		s.location, _ = locationOf(steps[0])
+	}
+
+	s.StopFn = func(p Player) bool { return s.current >= len(steps) }
+	s.ExecuteFn = func(p Player) (int, error) {
+		step := steps[s.current]
+		code, err := step.Execute(p)
+		if err != nil || code != http.StatusOK {
+			return code, err
+		}
+		if step.Stop(p) {
+			s.current += 1
+		}
+		return code, nil
+	}
+
+	return s
+}
+
+func locationOf(s Step) (*models.MapTile, bool) {
+	tile, ok := s.Location()
+	if !ok {
+		return nil, false
+	}
+	return &tile, true
+}
@@ -0,0 +1,236 @@
+package engine
+
+import (
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/player"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+)
+
+type GoalType string
+
+const (
+	GatherGoal  GoalType = "gather"
+	FightGoal   GoalType = "fight"
+	DepositGoal GoalType = "deposit"
+)
+
+// Goal is a unit of work requested from outside the engine, it is turned into a step when queued
+type Goal struct {
+	Type     GoalType `json:"type"`
+	Code     string   `json:"code"`
+	Quantity int      `json:"quantity"`
+}
+
+type PlayerStatus struct {
+	Name        string            `json:"name"`
+	Account     string            `json:"account"`
+	Data        player.PlayerData `json:"data"`
+	CurrentStep string            `json:"current_step"`
+	Queue       []string          `json:"queue"`
+	Paused      bool              `json:"paused"`
+}
+
+type BankStatus struct {
+	Account string                    `json:"account"`
+	Details client.BankSchema         `json:"details"`
+	Items   []client.SimpleItemSchema `json:"items"`
+	// Value is what the items would sell for at the grand exchange, gold not included
+	Value int `json:"value"`
+}
+
+type PlayerNotFound struct {
+	Name string
+}
+
+func (e PlayerNotFound) Error() string {
+	return fmt.Sprintf("player %s not found", e.Name)
+}
+
+func (e *GameEngine) Players() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	names := make([]string, 0, len(e.players))
+	for n := range e.players {
+		names = append(names, n)
+	}
+	return names
+}
+
+func (e *GameEngine) PlayerStatus(name string) (PlayerStatus, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.players[name]
+	if !ok {
+		return PlayerStatus{}, PlayerNotFound{Name: name}
+	}
+
+	queue := make([]string, 0, len(e.queue[name]))
+	for _, s := range e.queue[name] {
+		queue = append(queue, s.String())
+	}
+
+	return PlayerStatus{
+		Name:        name,
+		Account:     p.Account,
+		Data:        p.Data(),
+		CurrentStep: p.CurrentStep(),
+		Queue:       queue,
+		Paused:      e.paused[name],
+	}, nil
+}
+
+// Banks are the banks of every account sorted by account
+func (e *GameEngine) Banks() []BankStatus {
+	banks := make([]BankStatus, 0)
+	for _, b := range e.world.Banks() {
+		items := b.Items()
+		value := 0
+		for _, i := range items {
+			value += e.world.ItemPrice(i.Code) * i.Quantity
+		}
+		banks = append(banks, BankStatus{
+			Account: b.Account,
+			Details: b.Details(),
+			Items:   items,
+			Value:   value,
+		})
+	}
+	return banks
+}
+
+// Pause stops the player from receiving new commands once the current one completes
+func (e *GameEngine) Pause(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.players[name]; !ok {
+		return PlayerNotFound{Name: name}
+	}
+	e.paused[name] = true
+	e.notice(name, "paused")
+	return nil
+}
+
+// Resume releases a paused player, the response held while it was paused waits for a turn of its account
+func (e *GameEngine) Resume(name string) error {
+	p, ok := e.player(name)
+	if !ok {
+		return PlayerNotFound{Name: name}
+	}
+	e.mu.Lock()
+	delete(e.paused, name)
+	cr, held := e.held[name]
+	delete(e.held, name)
+	e.mu.Unlock()
+	e.notice(name, "resumed")
+
+	if held {
+		go e.schedule(cr, p)
+	}
+	return nil
+}
+
+// Enqueue adds a goal to the end of the player's queue
+func (e *GameEngine) Enqueue(name string, g Goal) error {
+	p, ok := e.player(name)
+	if !ok {
+		return PlayerNotFound{Name: name}
+	}
+
+	if err := e.validateGoal(g); err != nil {
+		return err
+	}
+
+	var step commands.Step
+	var err error
+	switch g.Type {
+	case GatherGoal:
+		step, err = e.newGatherStep(g.Code, g.Quantity, p)
+	case FightGoal:
+		step, err = e.newFightStep(g.Code, g.Quantity, p)
+	case DepositGoal:
+		step, err = e.newDepositStep(p)
+	default:
+		return fmt.Errorf("unknown goal type %s", g.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queue[name] = append(e.queue[name], step)
+	e.notice(name, fmt.Sprintf("queued %s", step))
+	return nil
+}
+
+// validateGoal checks the quantity and the code before anything is queued, steps with no quantity would stop at once or never
+func (e *GameEngine) validateGoal(g Goal) error {
+	switch g.Type {
+	case GatherGoal:
+		if e.world.GetResourceByName(g.Code) == nil {
+			return fmt.Errorf("unknown resource %s", g.Code)
+		}
+	case FightGoal:
+		if _, ok := e.world.GetMonster(g.Code); !ok {
+			return fmt.Errorf("unknown monster %s", g.Code)
+		}
+	case DepositGoal:
+		return nil
+	default:
+		return fmt.Errorf("unknown goal type %s", g.Type)
+	}
+	if g.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive, got %d", g.Quantity)
+	}
+	return nil
+}
+
+// ForceDeposit puts a deposit at the front of the player's queue
+func (e *GameEngine) ForceDeposit(name string) error {
+	p, ok := e.player(name)
+	if !ok {
+		return PlayerNotFound{Name: name}
+	}
+	step, err := e.newDepositStep(p)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queue[name] = append([]commands.Step{step}, e.queue[name]...)
+	e.notice(name, "forced deposit")
+	return nil
+}
+
+func (e *GameEngine) dequeue(name string) commands.Step {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	q := e.queue[name]
+	if len(q) == 0 {
+		return nil
+	}
+	e.queue[name] = q[1:]
+	return q[0]
+}
+
+// player looks up a player by name, the map is filled under the lock while the engine starts
+func (e *GameEngine) player(name string) (*player.Player, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	p, ok := e.players[name]
+	return p, ok
+}
+
+// hold keeps the response for a paused player until it is resumed, returns false if the player is not paused
+func (e *GameEngine) hold(cr events.CommandFinished) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.paused[cr.Character] {
+		return false
+	}
+	e.held[cr.Character] = cr
+	return true
+}
@@ -1,235 +1,470 @@
 package engine
 
 import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
 	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/history"
+	"artifactsmmo/internal/metrics"
 	"artifactsmmo/internal/models"
 	"artifactsmmo/internal/player"
+	"artifactsmmo/internal/ratelimit"
+	"artifactsmmo/internal/replay"
+	"artifactsmmo/internal/strategy"
 	"artifactsmmo/internal/world"
 	"context"
 	"fmt"
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
 	"github.com/sagikazarmark/slog-shim"
-	"math/rand"
-	"net/http"
+	"sync"
 )
 
 type GameEngine struct {
-	players   map[string]*player.Player
-	In        chan commands.CommandResponse
-	playerErr chan error
-	world     *world.Collector
-	ctx       context.Context
-	cancel    context.CancelFunc
-	Out       chan error
-	errChan   chan error
-	logger    *slog.Logger
+	players    map[string]*player.Player
+	bus        *events.Bus
+	world      *world.Collector
+	ctx        context.Context
+	cancel     context.CancelFunc
+	Out        chan error
+	logger     *slog.Logger
+	clock      clock.Clock
+	mu         sync.Mutex
+	queue      map[string][]commands.Step
+	paused     map[string]bool
+	held       map[string]events.CommandFinished
+	scorer     *strategy.Scorer
+	objectives map[string]strategy.Objective
+	planned    map[string]strategy.Candidate
+	taskPolicy strategy.TaskPolicy
+	scheduler  *scheduler
+	// failed counts the failed steps in a row per player, they are planned again until maxFailed and then paused
+	failed map[string]int
+}
+
+// maxFailed is how many steps in a row may fail and be planned again before the player is paused
+const maxFailed = 3
+
+// DefaultAccount names the account made of GameConfig's Token, PlayerNames and API
+const DefaultAccount = "default"
+
+// Account is a game account with its own token, characters and bank.
+// Every account gets its own client and rate limits, the world data is loaded once and shared.
+type Account struct {
+	Name    string   `yaml:"name"`
+	Token   string   `yaml:"token"`
+	Players []string `yaml:"players"`
+	// MaxActive is how many of the account's characters run a step at once, the others wait for a turn. Zero runs them all.
+	MaxActive int `yaml:"max_active" mapstructure:"max_active"`
+	// API replaces the client built from Token, e.g. with a fake
+	API api.GameAPI `yaml:"-" mapstructure:"-"`
 }
 
 type GameConfig struct {
 	Token       string
 	URL         string
 	PlayerNames []string
+	// MaxActive limits the default account like Account.MaxActive
+	MaxActive int
+	// Accounts are played next to the default account, leave Token and PlayerNames empty to only play these
+	Accounts []Account
+	// History is optional, when set actions are recorded and used to improve estimates
+	History *history.Store
+	// Objectives by player name, players without one use strategy.DefaultObjective
+	Objectives map[string]strategy.Objective
+	// TaskPolicy decides when tasks get cancelled and coins exchanged, unset fields use strategy.DefaultTaskPolicy
+	TaskPolicy strategy.TaskPolicy
+	// API replaces the client built from Token and URL, e.g. with a fake
+	API api.GameAPI
+	// Clock drives cooldowns and refreshes, nil uses the system clock
+	Clock clock.Clock
+	// Refresh sets how often world data is reloaded, unset intervals use world.DefaultRefreshConfig
+	Refresh world.RefreshConfig
+	// Cache is where static world data is kept between starts, the zero value downloads it every time
+	Cache world.Cache
+	// RateLimit sets the request budgets of the built client, unset fields use ratelimit.DefaultConfig
+	RateLimit ratelimit.Config
+	// Recorder is optional, when set every request of the built client is recorded with the response it got after retries
+	Recorder *replay.Recorder
 }
 
 func NewGameEngine(ctx context.Context, cfg GameConfig) (*GameEngine, error) {
-	gameCtx, cancel := context.WithCancel(ctx)
+	accounts, err := cfg.accounts()
+	if err != nil {
+		return nil, err
+	}
 
-	retryClient := retryablehttp.NewClient()
+	gameCtx, cancel := context.WithCancel(ctx)
 
-	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
-		shouldRetry, checkErr := retryablehttp.DefaultRetryPolicy(ctx, resp, err)
-		if shouldRetry || checkErr != nil {
-			return shouldRetry, err
+	for i, a := range accounts {
+		if a.API != nil {
+			continue
 		}
-
-		switch resp.StatusCode {
-		case 461:
-			//transaction already in progress with this at bank
-			return true, nil
-		case 486:
-			//character locked, action in progress
-			return true, nil
-		case 499:
-			//character in cooldown, shouldnt happen because we wait but can be retried
-			return true, nil
+		if accounts[i].API, err = newClient(cfg, a.Token); err != nil {
+			cancel()
+			return nil, fmt.Errorf("account %s: %w", a.Name, err)
 		}
-		return false, nil
 	}
 
-	c, err := client.NewClientWithResponses(cfg.URL,
-		client.WithRequestEditorFn(client.NewBearerAuthorizationRequestFunc(cfg.Token)),
-		client.WithHTTPClient(retryClient.HTTPClient),
-	)
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
 
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("cannot create client: %w", err)
+	bus := events.NewBus()
+	metrics.Subscribe(gameCtx, bus)
+	if cfg.History != nil {
+		cfg.History.Record(gameCtx, bus)
 	}
 
-	wc, err := world.NewCollector(ctx, c)
+	//static world data is the same for every account, the first one loads it
+	wc, err := world.NewCollector(gameCtx, accounts[0].API, bus, clk, cfg.Cache)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("cannot create world collector: %w", err)
 	}
+	for _, a := range accounts {
+		if err := wc.AddAccount(a.Name, a.API); err != nil {
+			cancel()
+			return nil, fmt.Errorf("cannot create world collector: %w", err)
+		}
+	}
+	wc.StartRefresh(cfg.Refresh)
 
-	engine := &GameEngine{
-		In:        make(chan commands.CommandResponse),
-		world:     wc,
-		ctx:       gameCtx,
-		cancel:    cancel,
-		errChan:   make(chan error),
-		Out:       make(chan error),
-		players:   map[string]*player.Player{},
-		logger:    slog.Default().With("source", "engine"),
-		playerErr: make(chan error),
+	limits := map[string]int{}
+	for _, a := range accounts {
+		limits[a.Name] = a.MaxActive
 	}
 
-	for _, name := range cfg.PlayerNames {
-		engine.logger.Debug(fmt.Sprintf("starting player %s", name))
-		p := player.NewPlayer(ctx, name, c, engine.In, wc.BankChannel, engine.playerErr)
-		engine.players[name] = p
+	engine := &GameEngine{
+		bus:        bus,
+		clock:      clk,
+		world:      wc,
+		ctx:        gameCtx,
+		cancel:     cancel,
+		Out:        make(chan error),
+		players:    map[string]*player.Player{},
+		logger:     slog.Default().With("source", "engine"),
+		queue:      map[string][]commands.Step{},
+		paused:     map[string]bool{},
+		held:       map[string]events.CommandFinished{},
+		scorer:     strategy.NewScorer(wc, cfg.History, clk),
+		objectives: cfg.Objectives,
+		planned:    map[string]strategy.Candidate{},
+		taskPolicy: cfg.TaskPolicy.WithDefaults(),
+		scheduler:  newScheduler(limits),
+		failed:     map[string]int{},
 	}
 
-	go engine.Start()
+	//subscribe before the players start so their first response is not missed
+	engine.Start()
 	go engine.MonitorForError()
 
+	//the lock keeps responses from being handled until every player is registered
+	engine.mu.Lock()
+	for _, a := range accounts {
+		for _, name := range a.Players {
+			engine.logger.Debug(fmt.Sprintf("starting player %s", name), "account", a.Name)
+			p := player.NewPlayer(gameCtx, name, a.Name, a.API, bus, clk)
+			engine.players[name] = p
+		}
+	}
+	engine.mu.Unlock()
+
 	return engine, nil
 }
 
+// accounts puts the default account first, followed by cfg.Accounts.
+// Names and characters must be unique, characters are found by name in the whole game.
+func (cfg GameConfig) accounts() ([]Account, error) {
+	accounts := make([]Account, 0, len(cfg.Accounts)+1)
+	if cfg.Token != "" || cfg.API != nil || len(cfg.PlayerNames) > 0 {
+		accounts = append(accounts, Account{Name: DefaultAccount, Token: cfg.Token, Players: cfg.PlayerNames, MaxActive: cfg.MaxActive, API: cfg.API})
+	}
+	accounts = append(accounts, cfg.Accounts...)
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no account configured")
+	}
+
+	names := map[string]bool{}
+	owners := map[string]string{}
+	for i, a := range accounts {
+		if a.Name == "" {
+			return nil, fmt.Errorf("account %d has no name", i)
+		}
+		if names[a.Name] {
+			return nil, fmt.Errorf("account %s configured twice", a.Name)
+		}
+		names[a.Name] = true
+		if a.Token == "" && a.API == nil {
+			return nil, fmt.Errorf("account %s has no token", a.Name)
+		}
+		for _, p := range a.Players {
+			if owner, ok := owners[p]; ok {
+				return nil, fmt.Errorf("character %s is in accounts %s and %s", p, owner, a.Name)
+			}
+			owners[p] = a.Name
+		}
+	}
+	return accounts, nil
+}
+
+// newClient builds the api client of an account with retries, rate limits, metrics and the optional recording.
+// The api limits every account on its own, so each client has its own budgets.
+func newClient(cfg GameConfig, token string) (api.GameAPI, error) {
+	retryClient := retryablehttp.NewClient()
+
+	retryClient.RetryMax = retryMax
+	retryClient.CheckRetry = checkRetry
+	retryClient.Backoff = backoff
+	retryClient.ErrorHandler = lastResponse
+	retryClient.Logger = slog.Default().With("source", "retry")
+
+	//retries wait for the rate limiter like every other request
+	retryClient.HTTPClient.Transport = actionTransport{
+		base: ratelimit.NewTransport(&metrics.Transport{Base: retryClient.HTTPClient.Transport}, cfg.RateLimit),
+	}
+
+	httpClient := retryClient.StandardClient()
+	if cfg.Recorder != nil {
+		//recorded above the retries, a replay without them answers with what the players got
+		httpClient.Transport = cfg.Recorder.Transport(httpClient.Transport)
+	}
+
+	c, err := client.NewClientWithResponses(cfg.URL,
+		client.WithRequestEditorFn(client.NewBearerAuthorizationRequestFunc(token)),
+		client.WithHTTPClient(httpClient),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client: %w", err)
+	}
+	return c, nil
+
+}
+
 func (e *GameEngine) MonitorForError() {
+	errs, unsubscribe := e.bus.Subscribe()
+	defer unsubscribe()
 	for {
 		select {
-		case err := <-e.errChan:
-			e.exitOnError(err)
+		case ev := <-errs:
+			if errEv, ok := ev.(events.ErrorOccurred); ok {
+				e.exitOnError(errEv.Err)
+			}
 		case err := <-e.world.Out:
 			e.exitOnError(err)
-		case err := <-e.Out:
-			e.logger.Error(err.Error())
 		case <-e.ctx.Done():
 			return
 		}
 	}
 }
 
+// Bus is the event bus shared by the engine, players and world collector
+func (e *GameEngine) Bus() *events.Bus {
+	return e.bus
+}
+
 // generatePlayerCommand determines the next step for a character given the character's state and previous instructions response
-func (e *GameEngine) generatePlayerCommand(resp commands.CommandResponse, player *player.Player) (commands.Step, error) {
+func (e *GameEngine) generatePlayerCommand(resp events.CommandFinished, player *player.Player) (commands.Step, error) {
 	// todo: eventually we should return a slice or chain of steps to follow
 
 	if resp.Code == 497 {
 		//player needs to deposit at the bank now
-		return e.newDepositStep()
-	} else if resp.Code != 200 && resp.Code != commands.PlayerStartedCode {
-		e.logger.Debug("got response from player", "code", resp.Code, "player", player.Name)
-		return nil, fmt.Errorf("player %s responded with %d", resp.Name, resp.Code)
-	} else {
-		//will this be an issue for crafting?
-		if player.InventoryCapacity() == 0 {
-			return e.newDepositStep()
+		return e.newDepositStep(player)
+	}
+	if resp.Code != 200 && resp.Code != commands.PlayerStartedCode && resp.Code != commands.ReconciledCode {
+		//the game turned the step down, e.g. 478 missing items or 493 skill too low, something else gets planned
+		e.logger.Warn("step failed, planning again", "code", resp.Code, "player", player.Name, "error", resp.Error)
+		e.notice(player.Name, fmt.Sprintf("step failed with %d, planning again", resp.Code))
+	}
+
+	//will this be an issue for crafting?
+	if player.InventoryCapacity() == 0 {
+		return e.newDepositStep(player)
+	}
+
+	if exchange, withdraw := e.scorer.ShouldExchange(player, e.taskPolicy); exchange {
+		if withdraw > 0 {
+			return e.newWithdrawStep(strategy.TaskCoin, withdraw, player)
 		}
+		return e.newExchangeTaskCoinsStep(player)
+	}
+
+	//events only last a while, go while they are up and the player qualifies
+	if c, ok := e.scorer.BestEvent(player, e.objective(player.Name)); ok {
+		e.logger.Info("selected event activity", "player", player.Name, "event", c.Event, "candidate", c.String())
+		return e.plan(player, c), nil
+	}
+
+	//does the player have an active task?
+	if player.Data().Task == nil {
+		return e.newAcceptTaskStep(player)
+	}
 
-		//does the player have an active task?
-		if player.Data().Task == nil {
-			return e.newAcceptTaskStep()
+	if player.Data().Task.Progress >= player.Data().Task.Total {
+		complete, err := e.newCompleteTaskStep(player)
+		if err != nil || player.InventoryCapacity() > player.Data().MaxInventory/2 {
+			return complete, err
 		}
+		//inventory is filling up, visit the bank on the same trip in whichever order is shorter
+		deposit, err := e.newDepositStep(player)
+		if err != nil {
+			return nil, err
+		}
+		return e.newRoute(player, complete, deposit), nil
+	}
 
-		if player.Data().Task.Progress >= player.Data().Task.Total {
-			return e.newCompleteTaskStep()
+	task := player.Data().Task
+
+	//todo: crafting
+	decision, c := e.scorer.Decide(player, e.taskPolicy)
+	switch decision {
+	case strategy.DoTask:
+		e.logger.Info("selected task activity", "player", player.Name, "task", task.Code, "candidate", c.String())
+		return e.plan(player, c), nil
+	case strategy.CancelTask:
+		e.logger.Info("task too costly, cancelling", "player", player.Name, "task", task.Code, "type", task.Type)
+		if player.CheckInventory(strategy.TaskCoin) == 0 {
+			return e.newWithdrawStep(strategy.TaskCoin, 1, player)
 		}
+		return e.newCancelTaskStep(player)
+	default:
+		e.logger.Info("task too costly and no coins to cancel, skipping task", "player", player.Name, "task", task.Code, "type", task.Type)
+		return e.newBestStep(player)
+	}
+}
 
-		task := player.Data().Task
+// newBestStep picks the activity with the best estimated return for the player's objective
+func (e *GameEngine) newBestStep(player *player.Player) (commands.Step, error) {
+	c, err := e.scorer.Best(player, e.objective(player.Name))
+	if err != nil {
+		return nil, err
+	}
+	e.logger.Info("selected activity", "player", player.Name, "candidate", c.String())
+	return e.plan(player, c), nil
+}
 
-		//todo: crafting
-		switch task.Type {
-		case "resources":
-			return e.newGatherStep(task.Code, task.Total-task.Progress, player)
-		case "monsters":
-			monster := e.world.GetMonster(task.Code)
-			if monster == nil {
-				return nil, fmt.Errorf("cannot find monster for: %s", task.Code)
-			}
+// plan remembers the candidate's estimates for the step planned event and returns its step
+func (e *GameEngine) plan(player *player.Player, c strategy.Candidate) commands.Step {
+	e.mu.Lock()
+	e.planned[player.Name] = c
+	e.mu.Unlock()
 
-			if player.CanWinFight(models.Earth, *monster) {
-				return e.newFightStep(task.Code, task.Total-task.Progress, player)
-			}
-			e.logger.Info("cannot win fight for given task, skipping task", "player", player.Name, "monster", task.Code)
-			fallthrough
-		default:
-			//todo: this default logic is temporary
-			//temporarily use 50/50 chance to fight random or gather some resource
-			if rand.Int()%2 == 0 { //resource gather
-				e.logger.Warn("unmapped task type", "type", player.Data().Task.Type)
-				//for now just prioritize lowest skill to mine
-				pData := player.Data()
-
-				skill := []string{models.WoodcuttingSkill, models.FishingSkill, models.MiningSkill}[rand.Intn(2)]
-				resources := e.world.GetResourcesBySkill(skill, pData.Skills[skill])
-
-				if len(resources) == 0 {
-					panic(fmt.Sprintf("no resources found for skill %s", skill))
-				}
-
-				return e.newGatherStep(resources[0].Code, rand.Intn(9)+1, player)
-			} else {
-				//temp code, fight random monster
-				monsters := e.world.FilterMonsters(player)
-				if len(monsters) == 0 {
-					return nil, fmt.Errorf("no fightable monsters for %s", player.Name)
-				}
-
-				i := rand.Intn(len(monsters))
-				if len(monsters) == 1 {
-					i = 0
-				}
-				m := monsters[i]
-
-				return e.newFightStep(m.Code, rand.Intn(9)+1, player)
-			}
+	if c.Type == strategy.FightCandidate {
+		return commands.NewFightStep(c.Quantity, c.Tile)
+	}
+	return commands.NewGatherStep(c.Quantity, c.Tile)
+}
 
+func (e *GameEngine) objective(name string) strategy.Objective {
+	if o, ok := e.objectives[name]; ok {
+		return o
+	}
+	return strategy.DefaultObjective()
+}
+
+// countFailed pauses a player whose plans keep failing, planning again would loop.
+// Only that player stops, its response is held until it is resumed and the other players keep running.
+func (e *GameEngine) countFailed(resp events.CommandFinished) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch resp.Code {
+	case 200, 497, commands.PlayerStartedCode:
+		delete(e.failed, resp.Character)
+		return
+	}
+	e.failed[resp.Character]++
+	if e.failed[resp.Character] <= maxFailed {
+		if resp.Code == commands.ReconciledCode {
+			e.logger.Info("planning again from the re-read character", "player", resp.Character)
 		}
+		return
 	}
+
+	e.logger.Warn("steps keep failing, pausing player", "player", resp.Character, "failed", e.failed[resp.Character], "code", resp.Code, "error", resp.Error)
+	delete(e.failed, resp.Character)
+	e.paused[resp.Character] = true
+	e.notice(resp.Character, fmt.Sprintf("paused after %d failed steps in a row, last with %d, resume to plan again", maxFailed+1, resp.Code))
 }
 
+// Start handles player responses, each one is answered with the player's next command
 func (e *GameEngine) Start() {
-	for {
-		select {
-		case cr := <-e.In:
-			p, ok := e.players[cr.Name]
-			if !ok {
-				e.exitOnError(fmt.Errorf("p %s not found", cr.Name))
-			}
-			e.logger.Debug(fmt.Sprintf("received code %d for player %s", cr.Code, cr.Name))
-			if cmd, err := e.generatePlayerCommand(cr, p); err != nil {
-				e.exitOnError(err)
-			} else {
-				p.In <- commands.Command{Steps: []commands.Step{cmd}}
-			}
-		default:
-			//loop
+	events.Handle(e.ctx, e.bus, func(cr events.CommandFinished) {
+		p, ok := e.player(cr.Character)
+		if !ok {
+			e.exitOnError(fmt.Errorf("p %s not found", cr.Character))
+			return
+		}
+		e.logger.Debug(fmt.Sprintf("received code %d for player %s", cr.Code, cr.Character))
+		e.countFailed(cr)
+		if e.hold(cr) {
+			e.scheduler.finished(p.Account, p.Name)
+			e.logger.Info("player paused, holding response", "player", cr.Character)
+			return
+		}
+		e.schedule(cr, p)
+	})
+}
+
+// schedule dispatches the players of the account whose turn it is, the player waits for its own when the account is at its limit
+func (e *GameEngine) schedule(cr events.CommandFinished, p *player.Player) {
+	for _, next := range e.scheduler.next(p.Account, cr, e.hold) {
+		if np, ok := e.player(next.Character); ok {
+			e.dispatch(next, np)
+		}
+	}
+}
+
+// dispatch sends the player its next command, queued goals take priority over generated steps
+func (e *GameEngine) dispatch(cr events.CommandFinished, p *player.Player) {
+	cmd := e.dequeue(p.Name)
+	if cmd == nil {
+		var err error
+		if cmd, err = e.generatePlayerCommand(cr, p); err != nil {
+			e.exitOnError(err)
+			return
 		}
 	}
+	planned := events.StepPlanned{Meta: events.NewMeta(p.Name, e.clock), Activity: cmd.Activity(), Step: cmd.String()}
+	e.mu.Lock()
+	if c, ok := e.planned[p.Name]; ok && c.Activity() == planned.Activity {
+		planned.XpPerHour = c.XpPerSecond() * 3600
+		planned.GoldPerHour = c.ValuePerSecond() * 3600
+	}
+	delete(e.planned, p.Name)
+	e.mu.Unlock()
+	e.bus.Publish(planned)
+	p.In <- commands.Command{Steps: []commands.Step{cmd}}
+}
+
+func (e *GameEngine) notice(player, message string) {
+	e.bus.Publish(events.Notice{Meta: events.NewMeta(player, e.clock), Message: message})
 }
 
-func (e *GameEngine) newDepositStep() (commands.Step, error) {
-	//find bank
-	tiles := e.world.GetMapByContentType(world.BankMapContentType)
+func (e *GameEngine) MapTiles() []models.MapTile {
+	return e.world.MapTiles()
+}
 
-	if len(tiles) == 0 {
-		return nil, fmt.Errorf("could not find bank")
+func (e *GameEngine) newDepositStep(player *player.Player) (commands.Step, error) {
+	bank, err := e.closest(world.BankMapContentType, player)
+	if err != nil {
+		return nil, err
 	}
-	return commands.NewDepositInventoryStep(*tiles[0]), nil
+	return commands.NewDepositInventoryStep(bank), nil
 }
 
 func (e *GameEngine) newGatherStep(resourceCode string, qty int, player *player.Player) (commands.Step, error) {
 	e.logger.Debug("filtering gather location")
 	pData := player.Data()
 
-	tile := e.world.FindClosestTile(resourceCode, pData.Pos.X, pData.Pos.Y)
-	if tile == nil {
+	tile, ok := e.world.FindClosestTile(resourceCode, pData.Pos.X, pData.Pos.Y)
+	if !ok {
 		return nil, fmt.Errorf("could not find tile for resource code %s", resourceCode)
 	}
 
-	return commands.NewGatherStep(qty, *tile), nil
+	return commands.NewGatherStep(qty, tile), nil
 }
 
 // todo: is this how we want to handle game loop errors?
@@ -247,27 +482,88 @@ func (e *GameEngine) newFightStep(monster string, qty int, player *player.Player
 	//find closest tile with the monster on it
 	//add fight step
 
-	tile := e.world.FindClosestTile(monster, player.Data().Pos.X, player.Data().Pos.Y)
-	if tile == nil {
+	tile, ok := e.world.FindClosestTile(monster, player.Data().Pos.X, player.Data().Pos.Y)
+	if !ok {
 		return nil, fmt.Errorf("could not find tile for monster %s", monster)
 	}
 
-	return commands.NewFightStep(qty, *tile), nil
+	return commands.NewFightStep(qty, tile), nil
+}
+
+func (e *GameEngine) newAcceptTaskStep(player *player.Player) (commands.Step, error) {
+	taskMaster, err := e.closest(world.TaskMasterContentType, player)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewAcceptTaskStep(taskMaster), nil
 }
 
-func (e *GameEngine) newAcceptTaskStep() (commands.Step, error) {
-	tiles := e.world.GetMapByContentType(world.TaskMasterContentType)
-	if len(tiles) == 0 {
-		return nil, fmt.Errorf("could not find task master")
+func (e *GameEngine) newCancelTaskStep(player *player.Player) (commands.Step, error) {
+	taskMaster, err := e.closest(world.TaskMasterContentType, player)
+	if err != nil {
+		return nil, err
 	}
-	return commands.NewAcceptTaskStep(*tiles[0]), nil
+	return commands.NewCancelTaskStep(taskMaster), nil
 }
 
-func (e *GameEngine) newCompleteTaskStep() (commands.Step, error) {
-	tiles := e.world.GetMapByContentType(world.TaskMasterContentType)
-	if len(tiles) == 0 {
-		return nil, fmt.Errorf("could not find task master")
+func (e *GameEngine) newExchangeTaskCoinsStep(player *player.Player) (commands.Step, error) {
+	taskMaster, err := e.closest(world.TaskMasterContentType, player)
+	if err != nil {
+		return nil, err
 	}
+	return commands.NewExchangeTaskCoinsStep(taskMaster), nil
+}
 
-	return commands.NewCompleteTaskStep(*tiles[0]), nil
+func (e *GameEngine) newWithdrawStep(code string, qty int, player *player.Player) (commands.Step, error) {
+	bank, err := e.closest(world.BankMapContentType, player)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewWithdrawStep(code, qty, bank), nil
+}
+
+func (e *GameEngine) newCompleteTaskStep(player *player.Player) (commands.Step, error) {
+	taskMaster, err := e.closest(world.TaskMasterContentType, player)
+	if err != nil {
+		return nil, err
+	}
+	return commands.NewCompleteTaskStep(taskMaster), nil
+}
+
+// closest finds the nearest tile of the content type to the player
+func (e *GameEngine) closest(contentType world.MapContentType, player *player.Player) (models.MapTile, error) {
+	x, y := player.Pos()
+	tile, ok := e.world.ClosestByContentType(contentType, x, y)
+	if !ok {
+		return models.MapTile{}, fmt.Errorf("could not find %s", contentType)
+	}
+	return tile, nil
+}
+
+// newRoute orders steps that can be done in any order by travel time from the player's position
+func (e *GameEngine) newRoute(player *player.Player, steps ...commands.Step) commands.Step {
+	stops := make([]models.MapTile, 0, len(steps))
+	byTile := map[models.TileID][]commands.Step{}
+	unplaced := make([]commands.Step, 0)
+	for _, s := range steps {
+		tile, ok := s.Location()
+		if !ok {
+			unplaced = append(unplaced, s)
+			continue
+		}
+		if _, seen := byTile[tile.ID()]; !seen {
+			stops = append(stops, tile)
+		}
+		byTile[tile.ID()] = append(byTile[tile.ID()], s)
+	}
+
+	x, y := player.Pos()
+	route := world.PlanRoute(x, y, stops)
+	e.logger.Debug("planned route", "player", player.Name, "stops", len(route.Stops), "seconds", route.Seconds)
+
+	ordered := make([]commands.Step, 0, len(steps))
+	for _, t := range route.Stops {
+		ordered = append(ordered, byTile[t.ID()]...)
+	}
+	return commands.NewSequenceStep(append(ordered, unplaced...)...)
 }
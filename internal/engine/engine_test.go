@@ -0,0 +1,313 @@
+package engine
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/fake"
+	"artifactsmmo/internal/models"
+	"artifactsmmo/internal/player"
+	"artifactsmmo/internal/ratelimit"
+	"artifactsmmo/internal/replay"
+	"artifactsmmo/internal/strategy"
+	"artifactsmmo/internal/world"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+)
+
+// TestEngineAgainstFake plays a character through retried and failed fights on the fake server without cooldowns.
+// The recording must only hold what the player got after retries, a replay has no retries.
+func TestEngineAgainstFake(t *testing.T) {
+	srv := fake.NewServer(fake.DefaultConfig("alice"))
+	defer srv.Close()
+	//the first fight is retried through a transaction, a lock and a cooldown, then the inventory is full
+	srv.Fail("alice", fake.ActionFight, codeTransactionInProgress, codeCharacterLocked, codeInCooldown, 497)
+
+	recording := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := replay.Create(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	game, err := NewGameEngine(ctx, GameConfig{
+		Token:       "test",
+		URL:         srv.URL,
+		PlayerNames: []string{"alice"},
+		RateLimit:   ratelimit.Config{Action: ratelimit.Budget{Rate: -1}, Data: ratelimit.Budget{Rate: -1}},
+		Recorder:    recorder,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planned, unsubscribe := game.Bus().Subscribe()
+	defer unsubscribe()
+
+	steps := make([]string, 0)
+	timeout := time.After(30 * time.Second)
+	for !slices.Contains(steps, "complete task") {
+		select {
+		case err := <-game.Out:
+			t.Fatalf("engine stopped: %s", err)
+		case <-timeout:
+			t.Fatalf("task was not completed, steps %v", steps)
+		case ev := <-planned:
+			if s, ok := ev.(events.StepPlanned); ok {
+				steps = append(steps, s.Step)
+			}
+		}
+	}
+	//hold the character once the task is handed in so its state stays put
+	if err = game.Pause("alice"); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := srv.Character("alice")
+	for status, _ := game.PlayerStatus("alice"); status.CurrentStep != "" || c.Task != ""; status, _ = game.PlayerStatus("alice") {
+		select {
+		case <-timeout:
+			t.Fatalf("task %s was not handed in", c.Task)
+		case <-time.After(10 * time.Millisecond):
+		}
+		c, _ = srv.Character("alice")
+	}
+
+	if c.Xp == 0 {
+		t.Error("no combat xp after winning fights")
+	}
+	items := map[string]int{}
+	for _, s := range *c.Inventory {
+		items[s.Code] += s.Quantity
+	}
+	//the full inventory error came before any fight was won, so everything since is still carried
+	if items["raw_chicken"] != 10 || items["tasks_coin"] != 1 {
+		t.Errorf("inventory has %d raw chicken and %d task coins, want 10 and 1", items["raw_chicken"], items["tasks_coin"])
+	}
+	if !slices.Contains(steps, "deposit inventory") {
+		t.Errorf("no deposit planned after the full inventory, steps %v", steps)
+	}
+
+	data, err := os.ReadFile(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := map[int]int{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var e replay.Exchange
+		if err = dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		codes[e.Status]++
+	}
+	for _, code := range []int{codeTransactionInProgress, codeCharacterLocked, codeInCooldown} {
+		if codes[code] > 0 {
+			t.Errorf("recorded %d responses with %d, retried responses must not be recorded", codes[code], code)
+		}
+	}
+	if codes[497] != 1 {
+		t.Errorf("recorded %d full inventory responses, want 1", codes[497])
+	}
+}
+
+// stubGame answers the calls a player and the collector make at start from a character and a bank, without http
+type stubGame struct {
+	api.GameAPI
+	char client.CharacterSchema
+	bank []client.SimpleItemSchema
+}
+
+func (s *stubGame) GetCharacterCharactersNameGetWithResponse(_ context.Context, _ string, _ ...client.RequestEditorFn) (*client.GetCharacterCharactersNameGetResponse, error) {
+	return &client.GetCharacterCharactersNameGetResponse{HTTPResponse: &http.Response{StatusCode: http.StatusOK}, JSON200: &client.CharacterResponseSchema{Data: s.char}}, nil
+}
+
+func (s *stubGame) GetAllEventsEventsGetWithResponse(_ context.Context, _ *client.GetAllEventsEventsGetParams, _ ...client.RequestEditorFn) (*client.GetAllEventsEventsGetResponse, error) {
+	var pages client.DataPageActiveEventSchema_Pages
+	if err := pages.FromDataPageActiveEventSchemaPages0(1); err != nil {
+		return nil, err
+	}
+	return &client.GetAllEventsEventsGetResponse{HTTPResponse: &http.Response{StatusCode: http.StatusOK}, JSON200: &client.DataPageActiveEventSchema{Pages: &pages}}, nil
+}
+
+func (s *stubGame) GetBankItemsMyBankItemsGetWithResponse(_ context.Context, _ *client.GetBankItemsMyBankItemsGetParams, _ ...client.RequestEditorFn) (*client.GetBankItemsMyBankItemsGetResponse, error) {
+	var page client.DataPageSimpleItemSchema_Page
+	if err := page.FromDataPageSimpleItemSchemaPage0(1); err != nil {
+		return nil, err
+	}
+	return &client.GetBankItemsMyBankItemsGetResponse{HTTPResponse: &http.Response{StatusCode: http.StatusOK}, JSON200: &client.DataPageSimpleItemSchema{Data: s.bank, Page: page}}, nil
+}
+
+func (s *stubGame) GetBankDetailsMyBankGetWithResponse(_ context.Context, _ ...client.RequestEditorFn) (*client.GetBankDetailsMyBankGetResponse, error) {
+	return &client.GetBankDetailsMyBankGetResponse{HTTPResponse: &http.Response{StatusCode: http.StatusOK}, JSON200: &client.BankResponseSchema{}}, nil
+}
+
+// newTestEngine builds an engine around one started player without handling its responses, the static world comes from a cached snapshot
+func newTestEngine(t *testing.T, stub *stubGame) (*GameEngine, *player.Player) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	cfg := fake.DefaultConfig()
+	cache := world.Cache{Path: filepath.Join(t.TempDir(), "world.json"), TTL: time.Hour}
+	snapshot := world.Snapshot{Version: world.SnapshotVersion, Created: clk.Now(), Tiles: cfg.Tiles, Monsters: cfg.Monsters, Resources: cfg.Resources, Items: cfg.Items}
+	if err := snapshot.Save(cache.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	bus := events.NewBus()
+	wc, err := world.NewCollector(ctx, stub, bus, clk, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wc.AddAccount(DefaultAccount, stub); err != nil {
+		t.Fatal(err)
+	}
+
+	p := player.NewPlayer(ctx, stub.char.Name, DefaultAccount, stub, bus, clk)
+	for p.Data().Skills == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	return &GameEngine{
+		bus:        bus,
+		clock:      clk,
+		world:      wc,
+		ctx:        ctx,
+		cancel:     cancel,
+		players:    map[string]*player.Player{p.Name: p},
+		logger:     slog.Default(),
+		queue:      map[string][]commands.Step{},
+		paused:     map[string]bool{},
+		held:       map[string]events.CommandFinished{},
+		scorer:     strategy.NewScorer(wc, nil, clk),
+		planned:    map[string]strategy.Candidate{},
+		taskPolicy: strategy.TaskPolicy{}.WithDefaults(),
+		scheduler:  newScheduler(nil),
+		failed:     map[string]int{},
+	}, p
+}
+
+func TestGeneratePlayerCommand(t *testing.T) {
+	full := []client.InventorySlot{{Slot: 1, Code: "raw_chicken", Quantity: 100}}
+	half := []client.InventorySlot{{Slot: 1, Code: "raw_chicken", Quantity: 60}}
+	coins := []client.InventorySlot{{Slot: 1, Code: strategy.TaskCoin, Quantity: 10}}
+	coin := []client.InventorySlot{{Slot: 1, Code: strategy.TaskCoin, Quantity: 1}}
+	chickens := &client.TaskSchema{Code: "chicken", Type: client.Monsters, Total: 10}
+	//wolves are not on the map, so the task cannot be done
+	wolves := &client.TaskSchema{Code: "wolf", Type: client.Monsters, Total: 10}
+
+	tests := []struct {
+		name      string
+		code      int
+		task      *client.TaskSchema
+		progress  int
+		inventory []client.InventorySlot
+		bank      []client.SimpleItemSchema
+		want      string
+	}{
+		{name: "inventory full error", code: 497, task: chickens, want: "deposit inventory"},
+		{name: "full inventory", code: http.StatusOK, task: chickens, inventory: full, want: "deposit inventory"},
+		{name: "coins carried", code: http.StatusOK, task: chickens, inventory: coins, want: "exchange task coins"},
+		{name: "coins in the bank", code: http.StatusOK, task: chickens, bank: []client.SimpleItemSchema{{Code: strategy.TaskCoin, Quantity: 10}}, want: "withdraw 3 tasks_coin"},
+		{name: "no task", code: commands.PlayerStartedCode, want: "accept task"},
+		{name: "task done", code: http.StatusOK, task: chickens, progress: 10, want: "complete task"},
+		{name: "task done with a filling inventory", code: http.StatusOK, task: chickens, progress: 10, inventory: half, want: "complete task, then deposit inventory"},
+		{name: "task in progress", code: http.StatusOK, task: chickens, progress: 3, want: "fight 7 chicken"},
+		{name: "failed step plans again", code: 478, task: chickens, progress: 3, want: "fight 7 chicken"},
+		{name: "reconciled step plans again", code: commands.ReconciledCode, task: chickens, progress: 3, want: "fight 7 chicken"},
+		{name: "impossible task with a coin carried", code: http.StatusOK, task: wolves, inventory: coin, want: "cancel task"},
+		{name: "impossible task with a coin in the bank", code: http.StatusOK, task: wolves, bank: []client.SimpleItemSchema{{Code: strategy.TaskCoin, Quantity: 1}}, want: "withdraw 1 tasks_coin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewCharacter("alice")
+			copy(*c.Inventory, tt.inventory)
+			if tt.task != nil {
+				c.Task, c.TaskType, c.TaskTotal, c.TaskProgress = tt.task.Code, string(tt.task.Type), tt.task.Total, tt.progress
+			}
+			e, p := newTestEngine(t, &stubGame{char: c, bank: tt.bank})
+
+			step, err := e.generatePlayerCommand(events.CommandFinished{Meta: events.NewMeta(p.Name, e.clock), Code: tt.code}, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if step.String() != tt.want {
+				t.Errorf("planned %q, want %q", step, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountFailed(t *testing.T) {
+	tests := []struct {
+		name  string
+		codes []int
+		//empty deposits are made after the codes, with the code the player returns
+		emptyDeposits int
+		wantPaused    bool
+	}{
+		{name: "planned again up to the limit", codes: []int{478, 493, 478}},
+		{name: "paused over the limit", codes: []int{478, 493, 478, 478}, wantPaused: true},
+		{name: "reconciled steps count", codes: []int{commands.ReconciledCode, commands.ReconciledCode, commands.ReconciledCode, commands.ReconciledCode}, wantPaused: true},
+		{name: "a step that works starts over", codes: []int{478, 478, 478, http.StatusOK, 478, 478, 478}},
+		{name: "full inventories do not count", codes: []int{497, 497, 497, 497, 497}},
+		{name: "deposits with nothing to deposit work", codes: []int{478, 478, 478}, emptyDeposits: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, p := newTestEngine(t, &stubGame{char: fake.NewCharacter("alice")})
+			published, unsubscribe := e.bus.Subscribe()
+			defer unsubscribe()
+
+			for _, code := range tt.codes {
+				e.countFailed(events.CommandFinished{Meta: events.NewMeta(p.Name, e.clock), Code: code})
+				//bob keeps working while alice fails
+				e.countFailed(events.CommandFinished{Meta: events.NewMeta("bob", e.clock), Code: http.StatusOK})
+			}
+			for range tt.emptyDeposits {
+				x, y := p.Pos()
+				e.countFailed(events.CommandFinished{Meta: events.NewMeta(p.Name, e.clock), Code: p.DepositInventory(models.MapTile{X: x, Y: y, Type: "bank", Code: "bank"})})
+			}
+			//the notice marks the end of what counting published
+			e.notice("", "done")
+
+			notices := make([]string, 0)
+			for ev := range published {
+				if n, ok := ev.(events.Notice); ok {
+					if n.Character == "" {
+						break
+					}
+					notices = append(notices, n.Message)
+				}
+			}
+
+			held := e.hold(events.CommandFinished{Meta: events.NewMeta(p.Name, e.clock)})
+			if held != tt.wantPaused {
+				t.Errorf("response held %t, want %t", held, tt.wantPaused)
+			}
+			if tt.wantPaused && len(notices) != 1 {
+				t.Errorf("notices %v, want one about the pause", notices)
+			}
+			if !tt.wantPaused && len(notices) != 0 {
+				t.Errorf("notices %v, want none", notices)
+			}
+			if e.hold(events.CommandFinished{Meta: events.NewMeta("bob", e.clock), Code: http.StatusOK}) {
+				t.Error("bob was paused")
+			}
+		})
+	}
+}
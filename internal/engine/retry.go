@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/hashicorp/go-retryablehttp"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// game error codes the retry policy handles
+const (
+	codeTransactionInProgress = 461
+	codeCharacterLocked       = 486
+	codeInCooldown            = 499
+)
+
+const (
+	retryMax = 4
+	// locks are held for the length of a request, short jittered waits keep characters from retrying in lockstep
+	lockRetryWait    = 250 * time.Millisecond
+	lockRetryMaxWait = 2 * time.Second
+	// cooldownMargin covers the rounding of the seconds left in the cooldown message
+	cooldownMargin = 100 * time.Millisecond
+)
+
+// cooldownLeft finds the seconds in messages like "Character in cooldown: 12.34 seconds left."
+var cooldownLeft = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?) seconds`)
+
+// gameError is the error the api sends with game codes, e.g. {"error":{"code":499,"message":"..."}}
+type gameError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errorBody is a response body read to parse the game error, the caller still gets the bytes
+type errorBody struct {
+	*bytes.Reader
+	gameError
+}
+
+func (errorBody) Close() error {
+	return nil
+}
+
+// unknownOutcome is an action that failed without an answer from the game, it may have been applied
+type unknownOutcome struct {
+	err error
+}
+
+func (e unknownOutcome) Error() string {
+	return "action outcome unknown: " + e.err.Error()
+}
+
+func (e unknownOutcome) Unwrap() error {
+	return e.err
+}
+
+// actionTransport marks failed actions, the retry policy only gets the error and could not tell them from data requests
+type actionTransport struct {
+	base http.RoundTripper
+}
+
+func (t actionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil && isAction(req) {
+		return nil, unknownOutcome{err: err}
+	}
+	return resp, err
+}
+
+func isAction(req *http.Request) bool {
+	return req != nil && req.Method == http.MethodPost && strings.Contains(req.URL.Path, "/action/")
+}
+
+// checkRetry retries what the game turned down without applying it: cooldowns, locks and rate limits.
+// Data requests also retry on errors. An action without an answer could move or fight twice if it was sent again,
+// so it goes back to the player, which re-reads its character instead.
+func checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	var unknown unknownOutcome
+	if errors.As(err, &unknown) {
+		return false, err
+	}
+	if err != nil || !isAction(resp.Request) {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+
+	switch resp.StatusCode {
+	case codeInCooldown, codeCharacterLocked, codeTransactionInProgress:
+		readGameError(resp)
+		return true, nil
+	case http.StatusTooManyRequests:
+		return true, nil
+	}
+	return false, nil
+}
+
+// backoff waits until the cooldown ends on 499 and a short random time on locks
+func backoff(minWait, maxWait time.Duration, attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if body, ok := resp.Body.(*errorBody); ok {
+			switch body.Code {
+			case codeInCooldown:
+				if m := cooldownLeft.FindStringSubmatch(body.Message); m != nil {
+					if s, err := strconv.ParseFloat(m[1], 64); err == nil {
+						return time.Duration(s*float64(time.Second)) + cooldownMargin
+					}
+				}
+			case codeCharacterLocked, codeTransactionInProgress:
+				return jitter(lockRetryWait, lockRetryMaxWait, attempt)
+			}
+		}
+	}
+	return retryablehttp.DefaultBackoff(minWait, maxWait, attempt, resp)
+}
+
+// lastResponse hands the last response to the caller once retries run out, players handle game codes themselves
+func lastResponse(resp *http.Response, err error, _ int) (*http.Response, error) {
+	if resp != nil {
+		return resp, nil
+	}
+	return nil, err
+}
+
+// readGameError swaps the body for an errorBody, a body that is not a game error is kept as is
+func readGameError(resp *http.Response) {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	body := &errorBody{Reader: bytes.NewReader(data)}
+	resp.Body = body
+	if err != nil {
+		return
+	}
+
+	var e struct {
+		Error gameError `json:"error"`
+	}
+	if json.Unmarshal(data, &e) == nil {
+		body.gameError = e.Error
+	}
+}
+
+// jitter doubles the wait every attempt up to the max and picks a random time between half of it and all of it
+func jitter(wait, maxWait time.Duration, attempt int) time.Duration {
+	d := min(maxWait, wait<<attempt)
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
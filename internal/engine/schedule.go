@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"artifactsmmo/internal/events"
+	"sync"
+)
+
+// scheduler takes turns between the players of an account, at most the account's limit of them run a step at once.
+// Players waiting for a turn go in the order their responses came, so every player of the account gets one.
+// Each account is scheduled on its own, a busy account never holds up the players of another.
+type scheduler struct {
+	mu sync.Mutex
+	// limits by account, accounts without one run every player at once
+	limits  map[string]int
+	running map[string]map[string]bool
+	waiting map[string][]events.CommandFinished
+}
+
+func newScheduler(limits map[string]int) *scheduler {
+	return &scheduler{
+		limits:  limits,
+		running: map[string]map[string]bool{},
+		waiting: map[string][]events.CommandFinished{},
+	}
+}
+
+// finished frees the turn of a player that is done with its step
+func (s *scheduler) finished(account, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running[account], name)
+}
+
+// next queues the response of a player done with its step and returns the responses whose players run now, oldest first.
+// held is asked before a waiting player gets its turn, a held response gives the turn to the next one.
+func (s *scheduler) next(account string, cr events.CommandFinished, held func(events.CommandFinished) bool) []events.CommandFinished {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	running, ok := s.running[account]
+	if !ok {
+		running = map[string]bool{}
+		s.running[account] = running
+	}
+	delete(running, cr.Character)
+	s.waiting[account] = append(s.waiting[account], cr)
+
+	ready := make([]events.CommandFinished, 0, 1)
+	limit := s.limits[account]
+	for len(s.waiting[account]) > 0 && (limit <= 0 || len(running) < limit) {
+		next := s.waiting[account][0]
+		s.waiting[account] = s.waiting[account][1:]
+		if held(next) {
+			continue
+		}
+		running[next.Character] = true
+		ready = append(ready, next)
+	}
+	return ready
+}
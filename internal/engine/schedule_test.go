@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"artifactsmmo/internal/events"
+	"slices"
+	"testing"
+)
+
+func TestScheduler(t *testing.T) {
+	type response struct {
+		account, name string
+		// ready are the players that run after the response, in order
+		ready []string
+	}
+	tests := []struct {
+		name      string
+		limits    map[string]int
+		paused    []string
+		responses []response
+	}{
+		{
+			name: "no limit runs every player",
+			responses: []response{
+				{"main", "alice", []string{"alice"}},
+				{"main", "bob", []string{"bob"}},
+				{"main", "alice", []string{"alice"}},
+			},
+		},
+		{
+			name:   "players of a limited account take turns",
+			limits: map[string]int{"main": 1},
+			responses: []response{
+				{"main", "alice", []string{"alice"}},
+				{"main", "bob", nil},
+				{"main", "carol", nil},
+				{"main", "alice", []string{"bob"}},
+				{"main", "bob", []string{"carol"}},
+				{"main", "carol", []string{"alice"}},
+			},
+		},
+		{
+			name:   "accounts are limited on their own",
+			limits: map[string]int{"main": 1, "alt": 1},
+			responses: []response{
+				{"main", "alice", []string{"alice"}},
+				{"main", "bob", nil},
+				{"alt", "dave", []string{"dave"}},
+				{"alt", "erin", nil},
+				{"alt", "dave", []string{"erin"}},
+				{"main", "alice", []string{"bob"}},
+			},
+		},
+		{
+			name:   "a limit above one fills every turn",
+			limits: map[string]int{"main": 2},
+			responses: []response{
+				{"main", "alice", []string{"alice"}},
+				{"main", "bob", []string{"bob"}},
+				{"main", "carol", nil},
+				{"main", "alice", []string{"carol"}},
+				{"main", "carol", []string{"alice"}},
+			},
+		},
+		{
+			name:   "a paused player gives its turn away",
+			limits: map[string]int{"main": 1},
+			paused: []string{"bob"},
+			responses: []response{
+				{"main", "alice", []string{"alice"}},
+				{"main", "bob", nil},
+				{"main", "carol", nil},
+				{"main", "alice", []string{"carol"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler(tt.limits)
+			held := func(cr events.CommandFinished) bool {
+				return slices.Contains(tt.paused, cr.Character)
+			}
+			for i, r := range tt.responses {
+				ready := make([]string, 0)
+				for _, cr := range s.next(r.account, events.CommandFinished{Meta: events.Meta{Character: r.name}}, held) {
+					ready = append(ready, cr.Character)
+				}
+				if !slices.Equal(ready, r.ready) {
+					t.Fatalf("response %d from %s ran %v, want %v", i, r.name, ready, r.ready)
+				}
+			}
+		})
+	}
+}
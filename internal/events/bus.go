@@ -0,0 +1,114 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Bus is an in-process publish/subscribe hub for game events.
+// Every subscriber gets its own unbounded queue so a slow subscriber never blocks a publisher or other subscribers.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+// Publish delivers the event to every current subscriber
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscribers {
+		s.push(e)
+	}
+}
+
+// Subscribe returns a channel receiving every event published from now on and a func to stop the subscription
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	s := &subscriber{
+		signal: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		out:    make(chan Event),
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	go s.run()
+
+	var once sync.Once
+	return s.out, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, s)
+			b.mu.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// Handle calls fn for every event of type T until the context is cancelled
+func Handle[T Event](ctx context.Context, b *Bus, fn func(T)) {
+	ch, unsubscribe := b.Subscribe()
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-ch:
+				if t, ok := e.(T); ok {
+					fn(t)
+				}
+			}
+		}
+	}()
+}
+
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Event
+	signal chan struct{}
+	done   chan struct{}
+	out    chan Event
+}
+
+func (s *subscriber) push(e Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) run() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.done:
+				return
+			case <-s.signal:
+				continue
+			}
+		}
+		e := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case s.out <- e:
+		}
+	}
+}
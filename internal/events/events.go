@@ -0,0 +1,220 @@
+package events
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/models"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"time"
+)
+
+// Event is anything published on the Bus
+type Event interface {
+	Name() string
+	Info() Meta
+	String() string
+}
+
+// Meta is shared by every event, Character is empty for events that are not tied to a character
+type Meta struct {
+	Character string    `json:"character"`
+	Time      time.Time `json:"time"`
+}
+
+// NewMeta stamps the event with the clock's time, so events of a simulation carry the simulated time
+func NewMeta(character string, clk clock.Clock) Meta {
+	return Meta{Character: character, Time: clk.Now()}
+}
+
+func (m Meta) Info() Meta {
+	return m
+}
+
+// CharacterUpdated is published whenever the api returns fresh character data
+type CharacterUpdated struct {
+	Meta
+	Schema client.CharacterSchema `json:"schema"`
+}
+
+func (e CharacterUpdated) Name() string { return "character_updated" }
+func (e CharacterUpdated) String() string {
+	return fmt.Sprintf("updated at %d,%d", e.Schema.X, e.Schema.Y)
+}
+
+type FightFinished struct {
+	Meta
+	Monster  string              `json:"monster"`
+	Win      bool                `json:"win"`
+	Turns    int                 `json:"turns"`
+	Xp       int                 `json:"xp"`
+	Gold     int                 `json:"gold"`
+	Drops    []client.DropSchema `json:"drops"`
+	Cooldown int                 `json:"cooldown"`
+}
+
+func (e FightFinished) Name() string { return "fight_finished" }
+func (e FightFinished) String() string {
+	result := "lost"
+	if e.Win {
+		result = "won"
+	}
+	return fmt.Sprintf("%s fight against %s in %d turns, %d xp %d gold", result, e.Monster, e.Turns, e.Xp, e.Gold)
+}
+
+type ItemGathered struct {
+	Meta
+	Resource string              `json:"resource"`
+	Skill    string              `json:"skill"`
+	Xp       int                 `json:"xp"`
+	Items    []client.DropSchema `json:"items"`
+	Cooldown int                 `json:"cooldown"`
+}
+
+func (e ItemGathered) Name() string { return "item_gathered" }
+func (e ItemGathered) String() string {
+	return fmt.Sprintf("gathered %s %v, %d xp", e.Resource, e.Items, e.Xp)
+}
+
+type TaskAccepted struct {
+	Meta
+	Task client.TaskSchema `json:"task"`
+}
+
+func (e TaskAccepted) Name() string { return "task_accepted" }
+func (e TaskAccepted) String() string {
+	return fmt.Sprintf("accepted task %s %d %s", e.Task.Type, e.Task.Total, e.Task.Code)
+}
+
+type TaskCompleted struct {
+	Meta
+	Reward client.TaskRewardSchema `json:"reward"`
+}
+
+func (e TaskCompleted) Name() string { return "task_completed" }
+func (e TaskCompleted) String() string {
+	return fmt.Sprintf("completed task, reward %d %s", e.Reward.Quantity, e.Reward.Code)
+}
+
+type TaskCoinsExchanged struct {
+	Meta
+	Reward client.TaskRewardSchema `json:"reward"`
+}
+
+func (e TaskCoinsExchanged) Name() string { return "task_coins_exchanged" }
+func (e TaskCoinsExchanged) String() string {
+	return fmt.Sprintf("exchanged task coins for %d %s", e.Reward.Quantity, e.Reward.Code)
+}
+
+type TaskCancelled struct {
+	Meta
+	Code string `json:"code"`
+	Type string `json:"type"`
+}
+
+func (e TaskCancelled) Name() string { return "task_cancelled" }
+func (e TaskCancelled) String() string {
+	return fmt.Sprintf("cancelled task %s %s", e.Type, e.Code)
+}
+
+// BankChanged carries the new state of an account's bank after a bank action, nil fields were not changed
+type BankChanged struct {
+	Meta
+	Account string                     `json:"account"`
+	Gold    *int                       `json:"gold,omitempty"`
+	Items   *[]client.SimpleItemSchema `json:"items,omitempty"`
+}
+
+func (e BankChanged) Name() string   { return "bank_changed" }
+func (e BankChanged) String() string { return "bank changed" }
+
+// MapChanged is published when a map refresh finds content that appeared or disappeared.
+// A tile whose content was replaced is in both lists, the old content in Disappeared and the new in Appeared.
+type MapChanged struct {
+	Meta
+	Appeared    []models.MapTile `json:"appeared"`
+	Disappeared []models.MapTile `json:"disappeared"`
+}
+
+func (e MapChanged) Name() string { return "map_changed" }
+func (e MapChanged) String() string {
+	return fmt.Sprintf("map changed, %d appeared %d disappeared", len(e.Appeared), len(e.Disappeared))
+}
+
+// GameEventStarted is published when a refresh finds a new timed game event
+type GameEventStarted struct {
+	Meta
+	Event models.GameEvent `json:"event"`
+}
+
+func (e GameEventStarted) Name() string { return "game_event_started" }
+func (e GameEventStarted) String() string {
+	return fmt.Sprintf("event %s at %d,%d until %s", e.Event.Name, e.Event.Tile.X, e.Event.Tile.Y, e.Event.Expiration.Format(time.TimeOnly))
+}
+
+// GameEventEnded is published when a game event is no longer listed
+type GameEventEnded struct {
+	Meta
+	Event models.GameEvent `json:"event"`
+}
+
+func (e GameEventEnded) Name() string { return "game_event_ended" }
+func (e GameEventEnded) String() string {
+	return fmt.Sprintf("event %s at %d,%d ended", e.Event.Name, e.Event.Tile.X, e.Event.Tile.Y)
+}
+
+// LevelUp is published when a skill level increases, the combat skill is the character level
+type LevelUp struct {
+	Meta
+	Skill string `json:"skill"`
+	Level int    `json:"level"`
+}
+
+func (e LevelUp) Name() string { return "level_up" }
+func (e LevelUp) String() string {
+	return fmt.Sprintf("%s level %d", e.Skill, e.Level)
+}
+
+// StepPlanned is published when the engine hands a step to a player.
+// Activity identifies what the step does, e.g. "gather ash_tree", and the estimates are zero when the engine made no prediction.
+type StepPlanned struct {
+	Meta
+	Activity    string  `json:"activity"`
+	Step        string  `json:"step"`
+	XpPerHour   float64 `json:"xp_per_hour"`
+	GoldPerHour float64 `json:"gold_per_hour"`
+}
+
+func (e StepPlanned) Name() string { return "step_planned" }
+func (e StepPlanned) String() string {
+	return fmt.Sprintf("starting %s", e.Step)
+}
+
+// CommandFinished is published by a player when it is ready for its next command
+type CommandFinished struct {
+	Meta
+	Code  int   `json:"code"`
+	Error error `json:"-"`
+}
+
+func (e CommandFinished) Name() string { return "command_finished" }
+func (e CommandFinished) String() string {
+	return fmt.Sprintf("finished with code %d", e.Code)
+}
+
+// ErrorOccurred is published for errors that should stop the game
+type ErrorOccurred struct {
+	Meta
+	Err error `json:"-"`
+}
+
+func (e ErrorOccurred) Name() string   { return "error" }
+func (e ErrorOccurred) String() string { return e.Err.Error() }
+
+// Notice is a free form message, used for control actions and decisions worth showing to a human
+type Notice struct {
+	Meta
+	Message string `json:"message"`
+}
+
+func (e Notice) Name() string   { return "notice" }
+func (e Notice) String() string { return e.Message }
@@ -0,0 +1,451 @@
+package fake
+
+import (
+	"artifactsmmo/internal/models"
+	"encoding/json"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+)
+
+// cooldowns of the real game used in virtual mode
+const (
+	maxFightTurns       = 100
+	moveSecondsPerTile  = 5
+	gatherSeconds       = 25
+	fightSecondsPerTurn = 2
+	actionSeconds       = 3
+)
+
+func (s *Server) move(c *character, r *http.Request) (any, int, string) {
+	var body client.DestinationSchema
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, http.StatusUnprocessableEntity, err.Error()
+	}
+	if c.X == body.X && c.Y == body.Y {
+		return nil, codeAlreadyAtLocation, "character already at destination"
+	}
+	distance := abs(c.X-body.X) + abs(c.Y-body.Y)
+	c.X, c.Y = body.X, body.Y
+
+	destination := client.MapSchema{X: body.X, Y: body.Y}
+	if t, ok := s.tileAt(body.X, body.Y); ok {
+		destination = mapSchema(t)
+	}
+	return client.CharacterMovementDataSchema{
+		Cooldown:    s.cooldown(c, client.CooldownSchemaReasonMovement, distance*moveSecondsPerTile),
+		Destination: destination,
+		Character:   cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) gather(c *character, _ *http.Request) (any, int, string) {
+	t, ok := s.tileAt(c.X, c.Y)
+	if !ok || t.Type != "resource" {
+		return nil, codeWrongTile, "resource not found on this map"
+	}
+	res, ok := s.resource(t.Code)
+	if !ok {
+		return nil, codeWrongTile, "resource not found on this map"
+	}
+	skill := string(res.Skill)
+	if skillLevel(&c.CharacterSchema, skill) < res.Level {
+		return nil, codeSkillTooLow, "not skill level required"
+	}
+
+	items := s.roll(res.Drops)
+	if !c.addItems(items) {
+		return nil, codeInventoryFull, "character inventory is full"
+	}
+	xp := xpFor(res.Level)
+	c.addXp(skill, xp)
+	if c.TaskType == string(client.Resources) {
+		for _, i := range items {
+			if i.Code == c.Task {
+				c.TaskProgress = min(c.TaskTotal, c.TaskProgress+i.Quantity)
+			}
+		}
+	}
+
+	return client.SkillDataSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonGathering, gatherSeconds),
+		Details:   client.SkillInfoSchema{Xp: xp, Items: items},
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) fight(c *character, _ *http.Request) (any, int, string) {
+	t, ok := s.tileAt(c.X, c.Y)
+	if !ok || t.Type != "monster" {
+		return nil, codeWrongTile, "monster not found on this map"
+	}
+	m, ok := s.monster(t.Code)
+	if !ok {
+		return nil, codeWrongTile, "monster not found on this map"
+	}
+	if inventoryCount(c.CharacterSchema) >= c.InventoryMaxItems {
+		return nil, codeInventoryFull, "character inventory is full"
+	}
+
+	win, turns := simulateFight(c.CharacterSchema, models.MonsterFromSchema(m))
+	fight := client.FightSchema{Turns: turns, Result: client.Lose, Drops: []client.DropSchema{}, Logs: []string{}}
+	if win {
+		fight.Result = client.Win
+		fight.Xp = xpFor(m.Level)
+		fight.Gold = m.MinGold
+		if m.MaxGold > m.MinGold {
+			fight.Gold += s.rng.Intn(m.MaxGold - m.MinGold + 1)
+		}
+		fight.Drops = s.roll(m.Drops)
+		c.addItems(fight.Drops)
+		c.Gold += fight.Gold
+		c.gains.Gold += fight.Gold
+		c.addXp(models.CombatSkill, fight.Xp)
+		if c.TaskType == string(client.Monsters) && c.Task == m.Code {
+			c.TaskProgress = min(c.TaskTotal, c.TaskProgress+1)
+		}
+	} else {
+		c.X, c.Y = 0, 0
+	}
+
+	return client.CharacterFightDataSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonFight, turns*fightSecondsPerTurn),
+		Fight:     fight,
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) deposit(c *character, r *http.Request) (any, int, string) {
+	var body client.SimpleItemSchema
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, http.StatusUnprocessableEntity, err.Error()
+	}
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "bank" {
+		return nil, codeWrongTile, "bank not found on this map"
+	}
+	if !removeItem(&c.CharacterSchema, body.Code, body.Quantity) {
+		return nil, codeMissingItem, "missing item or insufficient quantity"
+	}
+	s.bank = addBankItem(s.bank, body.Code, body.Quantity)
+
+	return client.BankItemTransactionSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonDepositBank, actionSeconds),
+		Item:      client.ItemSchema{Code: body.Code},
+		Bank:      append([]client.SimpleItemSchema{}, s.bank...),
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) withdraw(c *character, r *http.Request) (any, int, string) {
+	var body client.SimpleItemSchema
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, http.StatusUnprocessableEntity, err.Error()
+	}
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "bank" {
+		return nil, codeWrongTile, "bank not found on this map"
+	}
+	bank, ok := removeBankItem(s.bank, body.Code, body.Quantity)
+	if !ok {
+		return nil, http.StatusNotFound, "item not found"
+	}
+	if !addItems(&c.CharacterSchema, []client.DropSchema{{Code: body.Code, Quantity: body.Quantity}}) {
+		return nil, codeInventoryFull, "character inventory is full"
+	}
+	s.bank = bank
+
+	return client.BankItemTransactionSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonWithdrawBank, actionSeconds),
+		Item:      client.ItemSchema{Code: body.Code},
+		Bank:      append([]client.SimpleItemSchema{}, s.bank...),
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) newTask(c *character, _ *http.Request) (any, int, string) {
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "tasks_master" {
+		return nil, codeWrongTile, "tasks master not found on this map"
+	}
+	if c.Task != "" {
+		return nil, codeAlreadyHasTask, "character already has a task"
+	}
+	if len(s.cfg.Tasks) == 0 {
+		return nil, codeNotFound, "no tasks configured"
+	}
+	task := s.cfg.Tasks[s.tasks%len(s.cfg.Tasks)]
+	s.tasks++
+	c.Task, c.TaskType, c.TaskTotal, c.TaskProgress = task.Code, string(task.Type), task.Total, 0
+
+	return client.TaskDataSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonTask, actionSeconds),
+		Task:      task,
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) completeTask(c *character, _ *http.Request) (any, int, string) {
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "tasks_master" {
+		return nil, codeWrongTile, "tasks master not found on this map"
+	}
+	if c.Task == "" {
+		return nil, codeNoTask, "character has no task"
+	}
+	if c.TaskProgress < c.TaskTotal {
+		return nil, codeTaskNotComplete, "character has not completed the task"
+	}
+	reward := client.TaskRewardSchema{Code: taskCoin, Quantity: 1}
+	if !c.addItems([]client.DropSchema{{Code: reward.Code, Quantity: reward.Quantity}}) {
+		return nil, codeInventoryFull, "character inventory is full"
+	}
+	c.Task, c.TaskType, c.TaskTotal, c.TaskProgress = "", "", 0, 0
+
+	return client.TaskRewardDataSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonTask, actionSeconds),
+		Reward:    reward,
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) cancelTask(c *character, _ *http.Request) (any, int, string) {
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "tasks_master" {
+		return nil, codeWrongTile, "tasks master not found on this map"
+	}
+	if c.Task == "" {
+		return nil, codeNoTask, "character has no task"
+	}
+	if !removeItem(&c.CharacterSchema, taskCoin, taskCancelCost) {
+		return nil, codeMissingItem, "missing item or insufficient quantity"
+	}
+	c.Task, c.TaskType, c.TaskTotal, c.TaskProgress = "", "", 0, 0
+
+	return client.TaskCancelledSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonTask, actionSeconds),
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+func (s *Server) exchangeTask(c *character, _ *http.Request) (any, int, string) {
+	if t, ok := s.tileAt(c.X, c.Y); !ok || t.Type != "tasks_master" {
+		return nil, codeWrongTile, "tasks master not found on this map"
+	}
+	if len(s.cfg.ExchangeRewards) == 0 {
+		return nil, codeNotFound, "no exchange rewards configured"
+	}
+	if !removeItem(&c.CharacterSchema, taskCoin, taskExchangeCost) {
+		return nil, codeMissingItem, "missing item or insufficient quantity"
+	}
+	reward := s.cfg.ExchangeRewards[s.exchanges%len(s.cfg.ExchangeRewards)]
+	s.exchanges++
+	c.addItems([]client.DropSchema{{Code: reward.Code, Quantity: reward.Quantity}})
+
+	return client.TaskRewardDataSchema{
+		Cooldown:  s.cooldown(c, client.CooldownSchemaReasonTask, actionSeconds),
+		Reward:    client.TaskRewardSchema{Code: reward.Code, Quantity: reward.Quantity},
+		Character: cloneCharacter(c.CharacterSchema),
+	}, http.StatusOK, ""
+}
+
+// roll picks drops with the seeded random source, each drop has a 1 in rate chance
+func (s *Server) roll(drops []client.DropRateSchema) []client.DropSchema {
+	res := make([]client.DropSchema, 0, len(drops))
+	for _, d := range drops {
+		if d.Rate <= 0 || s.rng.Intn(d.Rate) != 0 {
+			continue
+		}
+		qty := d.MinQuantity
+		if d.MaxQuantity > d.MinQuantity {
+			qty += s.rng.Intn(d.MaxQuantity - d.MinQuantity + 1)
+		}
+		res = append(res, client.DropSchema{Code: d.Code, Quantity: qty})
+	}
+	return res
+}
+
+// simulateFight alternates turns starting with the character, the character heals fully after the fight
+func simulateFight(c client.CharacterSchema, m models.Monster) (bool, int) {
+	attacks := map[models.AttackType]int{
+		models.Fire:  c.AttackFire,
+		models.Water: c.AttackWater,
+		models.Earth: c.AttackEarth,
+		models.Air:   c.AttackAir,
+	}
+	resistances := map[models.AttackType]int{
+		models.Fire:  c.ResFire,
+		models.Water: c.ResWater,
+		models.Earth: c.ResEarth,
+		models.Air:   c.ResAir,
+	}
+	playerDmg := 0
+	for t, a := range attacks {
+		playerDmg += a * (100 - m.Resistances[t]) / 100
+	}
+	monsterDmg := m.AttackDmg * (100 - resistances[m.AttackType]) / 100
+
+	hp, monsterHp := c.Hp, m.Hp
+	for turn := 1; turn <= maxFightTurns; turn++ {
+		if turn%2 == 1 {
+			monsterHp -= playerDmg
+			if monsterHp <= 0 {
+				return true, turn
+			}
+		} else {
+			hp -= monsterDmg
+			if hp <= 0 {
+				return false, turn
+			}
+		}
+	}
+	return false, maxFightTurns
+}
+
+// addItems adds items the character earned, withdrawals use addItems directly so they are not counted
+func (c *character) addItems(items []client.DropSchema) bool {
+	if !addItems(&c.CharacterSchema, items) {
+		return false
+	}
+	for _, i := range items {
+		c.gains.Items[i.Code] += i.Quantity
+	}
+	return true
+}
+
+func (c *character) addXp(skill string, xp int) {
+	addSkillXp(&c.CharacterSchema, skill, xp)
+	c.gains.Xp[skill] += xp
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func xpFor(level int) int {
+	return 5 + level*2
+}
+
+func skillLevel(c *client.CharacterSchema, skill string) int {
+	return models.SkillLevels(*c)[skill]
+}
+
+// addSkillXp adds xp and levels the skill up, the combat skill is the character level
+func addSkillXp(c *client.CharacterSchema, skill string, xp int) {
+	var level, current, maxXp *int
+	switch skill {
+	case models.CombatSkill:
+		level, current, maxXp = &c.Level, &c.Xp, &c.MaxXp
+	case models.WoodcuttingSkill:
+		level, current, maxXp = &c.WoodcuttingLevel, &c.WoodcuttingXp, &c.WoodcuttingMaxXp
+	case models.MiningSkill:
+		level, current, maxXp = &c.MiningLevel, &c.MiningXp, &c.MiningMaxXp
+	case models.FishingSkill:
+		level, current, maxXp = &c.FishingLevel, &c.FishingXp, &c.FishingMaxXp
+	default:
+		return
+	}
+	*current += xp
+	for *current >= *maxXp {
+		*current -= *maxXp
+		*level++
+		*maxXp = *level * xpPerLevel
+	}
+}
+
+func inventoryCount(c client.CharacterSchema) int {
+	count := 0
+	for _, i := range *c.Inventory {
+		count += i.Quantity
+	}
+	return count
+}
+
+// addItems puts the items into the inventory, nothing is added when they do not fit
+func addItems(c *client.CharacterSchema, items []client.DropSchema) bool {
+	total := 0
+	for _, i := range items {
+		total += i.Quantity
+	}
+	if inventoryCount(*c)+total > c.InventoryMaxItems {
+		return false
+	}
+
+	//every new code needs a free slot, check them all before anything is added
+	inventory := *c.Inventory
+	free := 0
+	held := map[string]bool{}
+	for _, s := range inventory {
+		if s.Code == "" {
+			free++
+		} else {
+			held[s.Code] = true
+		}
+	}
+	for _, item := range items {
+		if !held[item.Code] {
+			held[item.Code] = true
+			free--
+		}
+	}
+	if free < 0 {
+		return false
+	}
+
+	for _, item := range items {
+		slot := -1
+		for i, s := range inventory {
+			if s.Code == item.Code {
+				slot = i
+				break
+			}
+			if s.Code == "" && slot < 0 {
+				slot = i
+			}
+		}
+		inventory[slot].Code = item.Code
+		inventory[slot].Quantity += item.Quantity
+	}
+	return true
+}
+
+func removeItem(c *client.CharacterSchema, code string, qty int) bool {
+	inventory := *c.Inventory
+	for i, s := range inventory {
+		if s.Code != code || s.Quantity < qty {
+			continue
+		}
+		inventory[i].Quantity -= qty
+		if inventory[i].Quantity == 0 {
+			inventory[i].Code = ""
+		}
+		return true
+	}
+	return false
+}
+
+func addBankItem(bank []client.SimpleItemSchema, code string, qty int) []client.SimpleItemSchema {
+	for i := range bank {
+		if bank[i].Code == code {
+			bank[i].Quantity += qty
+			return bank
+		}
+	}
+	return append(bank, client.SimpleItemSchema{Code: code, Quantity: qty})
+}
+
+func removeBankItem(bank []client.SimpleItemSchema, code string, qty int) ([]client.SimpleItemSchema, bool) {
+	res := make([]client.SimpleItemSchema, 0, len(bank))
+	found := false
+	for _, i := range bank {
+		if i.Code == code {
+			if i.Quantity < qty {
+				return bank, false
+			}
+			found = true
+			i.Quantity -= qty
+			if i.Quantity == 0 {
+				continue
+			}
+		}
+		res = append(res, i)
+	}
+	return res, found
+}
@@ -0,0 +1,53 @@
+package fake
+
+import (
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"slices"
+	"testing"
+)
+
+func TestAddItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		inventory []client.InventorySlot
+		max       int
+		items     []client.DropSchema
+		ok        bool
+		want      []client.InventorySlot
+	}{
+		{
+			name:      "stacks and fills a free slot",
+			inventory: []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 1}, {Slot: 2}},
+			max:       10,
+			items:     []client.DropSchema{{Code: "feather", Quantity: 2}, {Code: "raw_chicken", Quantity: 1}},
+			ok:        true,
+			want:      []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 3}, {Slot: 2, Code: "raw_chicken", Quantity: 1}},
+		},
+		{
+			name:      "over the item limit",
+			inventory: []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 9}, {Slot: 2}},
+			max:       10,
+			items:     []client.DropSchema{{Code: "feather", Quantity: 2}},
+			want:      []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 9}, {Slot: 2}},
+		},
+		{
+			name:      "no free slot for the second code",
+			inventory: []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 1}, {Slot: 2}},
+			max:       10,
+			items:     []client.DropSchema{{Code: "feather", Quantity: 1}, {Code: "raw_chicken", Quantity: 1}, {Code: "egg", Quantity: 1}},
+			want:      []client.InventorySlot{{Slot: 1, Code: "feather", Quantity: 1}, {Slot: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventory := slices.Clone(tt.inventory)
+			c := client.CharacterSchema{Inventory: &inventory, InventoryMaxItems: tt.max}
+			if ok := addItems(&c, tt.items); ok != tt.ok {
+				t.Errorf("addItems returned %t, want %t", ok, tt.ok)
+			}
+			if !slices.Equal(inventory, tt.want) {
+				t.Errorf("inventory is %+v, want %+v", inventory, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package fake
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/models"
+	"encoding/json"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"maps"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Action is the path of an action endpoint after /my/{name}/action/, used to inject errors
+type Action string
+
+const (
+	ActionMove         Action = "move"
+	ActionGather       Action = "gathering"
+	ActionFight        Action = "fight"
+	ActionDeposit      Action = "bank/deposit"
+	ActionWithdraw     Action = "bank/withdraw"
+	ActionTaskNew      Action = "task/new"
+	ActionTaskComplete Action = "task/complete"
+	ActionTaskCancel   Action = "task/cancel"
+	ActionTaskExchange Action = "task/exchange"
+)
+
+// game error codes the server returns
+const (
+	codeMissingItem       = 478
+	codeTaskNotComplete   = 488
+	codeAlreadyHasTask    = 489
+	codeAlreadyAtLocation = 490
+	codeSkillTooLow       = 493
+	codeInventoryFull     = 497
+	codeNotFound          = 498
+	codeInCooldown        = 499
+	codeNoTask            = 487
+	codeWrongTile         = 598
+)
+
+const (
+	taskExchangeCost = 3
+	taskCancelCost   = 1
+	taskCoin         = "tasks_coin"
+)
+
+// Config is the starting state of the fake world
+type Config struct {
+	Tiles      []models.MapTile
+	Monsters   []client.MonsterSchema
+	Resources  []client.ResourceSchema
+	Items      []client.GEItemSchema
+	Events     []models.GameEvent
+	Characters []client.CharacterSchema
+	Bank       []client.SimpleItemSchema
+	BankGold   int
+	// Tasks are handed out in order, starting over after the last one
+	Tasks []client.TaskSchema
+	// ExchangeRewards are handed out in order for task coin exchanges
+	ExchangeRewards []client.SimpleItemSchema
+	// Cooldown is the cooldown of every action in seconds, zero lets tests run without waiting
+	Cooldown int
+	// GameCooldowns replaces Cooldown with what each action takes in the real game, e.g. 5 seconds per tile moved
+	GameCooldowns bool
+	// Clock is the server's time for cooldowns and events, nil uses the system clock
+	Clock clock.Clock
+	// Seed makes drops repeatable
+	Seed int64
+}
+
+// Server is an in memory ArtifactsMMO api for running the engine offline
+type Server struct {
+	*httptest.Server
+	mu         sync.Mutex
+	cfg        Config
+	characters map[string]*character
+	bank       []client.SimpleItemSchema
+	rng        *rand.Rand
+	failures   map[string][]int
+	tasks      int
+	exchanges  int
+}
+
+type character struct {
+	client.CharacterSchema
+	cooldownExpiration time.Time
+	elapsed            time.Duration
+	gains              Gains
+}
+
+// Gains is what a character earned since the server started
+type Gains struct {
+	// Xp by skill, the combat skill is character xp
+	Xp    map[string]int
+	Gold  int
+	Items map[string]int
+}
+
+func newCharacter(c client.CharacterSchema) *character {
+	return &character{
+		CharacterSchema: cloneCharacter(c),
+		gains:           Gains{Xp: map[string]int{}, Items: map[string]int{}},
+	}
+}
+
+// NewServer starts a server with the config, close it with Close
+func NewServer(cfg Config) *Server {
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real{}
+	}
+	s := &Server{
+		cfg:        cfg,
+		characters: map[string]*character{},
+		bank:       append([]client.SimpleItemSchema{}, cfg.Bank...),
+		rng:        rand.New(rand.NewSource(cfg.Seed)),
+		failures:   map[string][]int{},
+	}
+	for _, c := range cfg.Characters {
+		s.characters[c.Name] = newCharacter(c)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /characters/{name}", s.getCharacter)
+	mux.HandleFunc("POST /characters/create", s.createCharacter)
+	mux.HandleFunc("GET /maps", s.getMaps)
+	mux.HandleFunc("GET /monsters", s.getMonsters)
+	mux.HandleFunc("GET /resources", s.getResources)
+	mux.HandleFunc("GET /ge", s.getItems)
+	mux.HandleFunc("GET /events", s.getEvents)
+	mux.HandleFunc("GET /my/bank", s.getBank)
+	mux.HandleFunc("GET /my/bank/items", s.getBankItems)
+	mux.HandleFunc("POST /my/{name}/action/move", s.action(ActionMove, s.move))
+	mux.HandleFunc("POST /my/{name}/action/gathering", s.action(ActionGather, s.gather))
+	mux.HandleFunc("POST /my/{name}/action/fight", s.action(ActionFight, s.fight))
+	mux.HandleFunc("POST /my/{name}/action/bank/deposit", s.action(ActionDeposit, s.deposit))
+	mux.HandleFunc("POST /my/{name}/action/bank/withdraw", s.action(ActionWithdraw, s.withdraw))
+	mux.HandleFunc("POST /my/{name}/action/task/new", s.action(ActionTaskNew, s.newTask))
+	mux.HandleFunc("POST /my/{name}/action/task/complete", s.action(ActionTaskComplete, s.completeTask))
+	mux.HandleFunc("POST /my/{name}/action/task/cancel", s.action(ActionTaskCancel, s.cancelTask))
+	mux.HandleFunc("POST /my/{name}/action/task/exchange", s.action(ActionTaskExchange, s.exchangeTask))
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Fail makes the next actions of the character respond with the codes, one code per request
+func (s *Server) Fail(name string, action Action, codes ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := failureKey(name, action)
+	s.failures[key] = append(s.failures[key], codes...)
+}
+
+// SetCooldown changes the cooldown of every following action
+func (s *Server) SetCooldown(seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg.Cooldown = seconds
+}
+
+// Character returns the server side state of the character
+func (s *Server) Character(name string) (client.CharacterSchema, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.characters[name]
+	if !ok {
+		return client.CharacterSchema{}, false
+	}
+	return cloneCharacter(c.CharacterSchema), true
+}
+
+// Elapsed is the total cooldown the character's actions took
+func (s *Server) Elapsed(name string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.characters[name]; ok {
+		return c.elapsed
+	}
+	return 0
+}
+
+// Gains is the xp, gold and items the character got from gathering, fights and tasks
+func (s *Server) Gains(name string) Gains {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := Gains{Xp: map[string]int{}, Items: map[string]int{}}
+	if c, ok := s.characters[name]; ok {
+		maps.Copy(res.Xp, c.gains.Xp)
+		maps.Copy(res.Items, c.gains.Items)
+		res.Gold = c.gains.Gold
+	}
+	return res
+}
+
+// BankItems returns the server side bank contents
+func (s *Server) BankItems() []client.SimpleItemSchema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]client.SimpleItemSchema{}, s.bank...)
+}
+
+func failureKey(name string, action Action) string {
+	return name + " " + string(action)
+}
+
+type actionFunc func(c *character, r *http.Request) (any, int, string)
+
+// action handles what every action endpoint shares: the character lookup, injected errors, cooldowns and the response
+func (s *Server) action(action Action, fn actionFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		name := r.PathValue("name")
+		c, ok := s.characters[name]
+		if !ok {
+			writeError(w, http.StatusNotFound, "character not found")
+			return
+		}
+
+		key := failureKey(name, action)
+		if codes := s.failures[key]; len(codes) > 0 {
+			s.failures[key] = codes[1:]
+			writeError(w, codes[0], "injected error")
+			return
+		}
+
+		if remaining := c.cooldownExpiration.Sub(s.cfg.Clock.Now()); remaining > 0 {
+			writeError(w, codeInCooldown, fmt.Sprintf("character in cooldown: %.2f seconds left", remaining.Seconds()))
+			return
+		}
+
+		data, code, msg := fn(c, r)
+		if code != http.StatusOK {
+			writeError(w, code, msg)
+			return
+		}
+		writeJSON(w, map[string]any{"data": data})
+	}
+}
+
+// cooldown starts the configured cooldown on the character, game is what the action would take in the real game
+func (s *Server) cooldown(c *character, reason client.CooldownSchemaReason, game int) client.CooldownSchema {
+	seconds := s.cfg.Cooldown
+	if s.cfg.GameCooldowns {
+		seconds = game
+	}
+	c.elapsed += time.Duration(seconds) * time.Second
+
+	now := s.cfg.Clock.Now()
+	c.cooldownExpiration = now.Add(time.Duration(seconds) * time.Second)
+	c.Cooldown = seconds
+	expiration := c.cooldownExpiration
+	c.CooldownExpiration = &expiration
+	return client.CooldownSchema{
+		Expiration:       c.cooldownExpiration,
+		Reason:           reason,
+		RemainingSeconds: seconds,
+		StartedAt:        now,
+		TotalSeconds:     seconds,
+	}
+}
+
+func (s *Server) getCharacter(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.characters[r.PathValue("name")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "character not found")
+		return
+	}
+	writeJSON(w, map[string]any{"data": cloneCharacter(c.CharacterSchema)})
+}
+
+func (s *Server) createCharacter(w http.ResponseWriter, r *http.Request) {
+	var body client.AddCharacterSchema
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.characters[body.Name]; ok {
+		writeError(w, 494, "name already used")
+		return
+	}
+	c := NewCharacter(body.Name)
+	c.Skin = client.CharacterSchemaSkin(body.Skin)
+	s.characters[body.Name] = newCharacter(c)
+	writeJSON(w, map[string]any{"data": cloneCharacter(c)})
+}
+
+// page answers list endpoints with the page and size query parameters like the real api
+func page[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	p, size := 1, 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && v > 0 {
+		p = v
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("size")); err == nil && v > 0 {
+		size = v
+	}
+
+	pages := (len(items) + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	start := min((p-1)*size, len(items))
+	end := min(start+size, len(items))
+
+	writeJSON(w, struct {
+		Data  []T `json:"data"`
+		Page  int `json:"page"`
+		Pages int `json:"pages"`
+		Size  int `json:"size"`
+		Total int `json:"total"`
+	}{items[start:end], p, pages, size, len(items)})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": code, "message": msg},
+	})
+}
@@ -0,0 +1,209 @@
+package fake
+
+import (
+	"artifactsmmo/internal/models"
+	"artifactsmmo/internal/world"
+	"cmp"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+	"slices"
+)
+
+const (
+	startingHp        = 120
+	startingAttack    = 4
+	inventoryMaxItems = 100
+	inventorySlots    = 20
+	xpPerLevel        = 150
+	snapshotTaskTotal = 20
+)
+
+// NewCharacter returns a level 1 character at 0,0 with an empty inventory
+func NewCharacter(name string) client.CharacterSchema {
+	inventory := make([]client.InventorySlot, inventorySlots)
+	for i := range inventory {
+		inventory[i].Slot = i + 1
+	}
+	return client.CharacterSchema{
+		Name:                 name,
+		Level:                1,
+		MaxXp:                xpPerLevel,
+		Hp:                   startingHp,
+		AttackEarth:          startingAttack,
+		InventoryMaxItems:    inventoryMaxItems,
+		Inventory:            &inventory,
+		Skin:                 "men1",
+		CookingLevel:         1,
+		CookingMaxXp:         xpPerLevel,
+		FishingLevel:         1,
+		FishingMaxXp:         xpPerLevel,
+		GearcraftingLevel:    1,
+		GearcraftingMaxXp:    xpPerLevel,
+		JewelrycraftingLevel: 1,
+		JewelrycraftingMaxXp: xpPerLevel,
+		MiningLevel:          1,
+		MiningMaxXp:          xpPerLevel,
+		WeaponcraftingLevel:  1,
+		WeaponcraftingMaxXp:  xpPerLevel,
+		WoodcuttingLevel:     1,
+		WoodcuttingMaxXp:     xpPerLevel,
+	}
+}
+
+// DefaultConfig is a small starting area with a bank, a taskmaster, a monster and one resource per gathering skill
+func DefaultConfig(characters ...string) Config {
+	cfg := Config{
+		Tiles: []models.MapTile{
+			{X: 4, Y: 1, Type: "bank", Code: "bank"},
+			{X: 1, Y: 2, Type: "tasks_master", Code: "monsters"},
+			{X: 0, Y: 1, Type: "monster", Code: "chicken"},
+			{X: -1, Y: 0, Type: "resource", Code: "ash_tree"},
+			{X: 2, Y: 0, Type: "resource", Code: "copper_rocks"},
+			{X: 4, Y: 2, Type: "resource", Code: "gudgeon_fishing_spot"},
+		},
+		Monsters: []client.MonsterSchema{{
+			Name:        "Chicken",
+			Code:        "chicken",
+			Level:       1,
+			Hp:          60,
+			AttackWater: 4,
+			MinGold:     0,
+			MaxGold:     3,
+			Drops: []client.DropRateSchema{
+				{Code: "raw_chicken", Rate: 1, MinQuantity: 1, MaxQuantity: 1},
+				{Code: "feather", Rate: 8, MinQuantity: 1, MaxQuantity: 2},
+			},
+		}},
+		Resources: []client.ResourceSchema{
+			{Name: "Ash Tree", Code: "ash_tree", Skill: "woodcutting", Level: 1, Drops: []client.DropRateSchema{{Code: "ash_wood", Rate: 1, MinQuantity: 1, MaxQuantity: 1}}},
+			{Name: "Copper Rocks", Code: "copper_rocks", Skill: "mining", Level: 1, Drops: []client.DropRateSchema{{Code: "copper_ore", Rate: 1, MinQuantity: 1, MaxQuantity: 1}}},
+			{Name: "Gudgeon Fishing Spot", Code: "gudgeon_fishing_spot", Skill: "fishing", Level: 1, Drops: []client.DropRateSchema{{Code: "gudgeon", Rate: 1, MinQuantity: 1, MaxQuantity: 1}}},
+		},
+		Items: []client.GEItemSchema{
+			{Code: "raw_chicken", SellPrice: ptr(2)},
+			{Code: "feather", SellPrice: ptr(3)},
+			{Code: "ash_wood", SellPrice: ptr(1)},
+			{Code: "copper_ore", SellPrice: ptr(2)},
+			{Code: "gudgeon", SellPrice: ptr(2)},
+		},
+		Tasks: []client.TaskSchema{
+			{Code: "chicken", Type: client.Monsters, Total: 10},
+		},
+		ExchangeRewards: []client.SimpleItemSchema{{Code: "small_health_potion", Quantity: 1}},
+	}
+	for _, name := range characters {
+		cfg.Characters = append(cfg.Characters, NewCharacter(name))
+	}
+	return cfg
+}
+
+// FromSnapshot builds the world from a saved snapshot, tasks go through the monsters from the lowest level up
+func FromSnapshot(snapshot world.Snapshot, characters ...client.CharacterSchema) Config {
+	monsters := slices.Clone(snapshot.Monsters)
+	slices.SortStableFunc(monsters, func(a, b client.MonsterSchema) int {
+		return cmp.Compare(a.Level, b.Level)
+	})
+	tasks := make([]client.TaskSchema, 0, len(monsters))
+	for _, m := range monsters {
+		tasks = append(tasks, client.TaskSchema{Code: m.Code, Type: client.Monsters, Total: snapshotTaskTotal})
+	}
+
+	return Config{
+		Tiles:           snapshot.Tiles,
+		Monsters:        snapshot.Monsters,
+		Resources:       snapshot.Resources,
+		Items:           snapshot.Items,
+		Characters:      characters,
+		Tasks:           tasks,
+		ExchangeRewards: DefaultConfig().ExchangeRewards,
+	}
+}
+
+func (s *Server) getMaps(w http.ResponseWriter, r *http.Request) {
+	maps := make([]client.MapSchema, 0, len(s.cfg.Tiles))
+	for _, t := range s.cfg.Tiles {
+		maps = append(maps, mapSchema(t))
+	}
+	page(w, r, maps)
+}
+
+func (s *Server) getMonsters(w http.ResponseWriter, r *http.Request) {
+	page(w, r, s.cfg.Monsters)
+}
+
+func (s *Server) getResources(w http.ResponseWriter, r *http.Request) {
+	page(w, r, s.cfg.Resources)
+}
+
+func (s *Server) getItems(w http.ResponseWriter, r *http.Request) {
+	page(w, r, s.cfg.Items)
+}
+
+func (s *Server) getEvents(w http.ResponseWriter, r *http.Request) {
+	res := make([]client.ActiveEventSchema, 0, len(s.cfg.Events))
+	now := s.cfg.Clock.Now()
+	for _, e := range s.cfg.Events {
+		if !e.Active(now) {
+			continue
+		}
+		res = append(res, client.ActiveEventSchema{
+			Name:       e.Name,
+			Map:        mapSchema(e.Tile),
+			Expiration: e.Expiration,
+		})
+	}
+	page(w, r, res)
+}
+
+func (s *Server) getBank(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, map[string]any{"data": client.BankSchema{Gold: s.cfg.BankGold, Slots: 50}})
+}
+
+func (s *Server) getBankItems(w http.ResponseWriter, r *http.Request) {
+	page(w, r, s.BankItems())
+}
+
+func (s *Server) tileAt(x, y int) (models.MapTile, bool) {
+	for _, t := range s.cfg.Tiles {
+		if t.X == x && t.Y == y {
+			return t, true
+		}
+	}
+	return models.MapTile{}, false
+}
+
+func (s *Server) monster(code string) (client.MonsterSchema, bool) {
+	i := slices.IndexFunc(s.cfg.Monsters, func(m client.MonsterSchema) bool { return m.Code == code })
+	if i < 0 {
+		return client.MonsterSchema{}, false
+	}
+	return s.cfg.Monsters[i], true
+}
+
+func (s *Server) resource(code string) (client.ResourceSchema, bool) {
+	i := slices.IndexFunc(s.cfg.Resources, func(r client.ResourceSchema) bool { return r.Code == code })
+	if i < 0 {
+		return client.ResourceSchema{}, false
+	}
+	return s.cfg.Resources[i], true
+}
+
+func mapSchema(t models.MapTile) client.MapSchema {
+	m := client.MapSchema{X: t.X, Y: t.Y}
+	_ = m.Content.FromMapContentSchema(client.MapContentSchema{Type: t.Type, Code: t.Code})
+	return m
+}
+
+func cloneCharacter(c client.CharacterSchema) client.CharacterSchema {
+	if c.Inventory != nil {
+		inventory := slices.Clone(*c.Inventory)
+		c.Inventory = &inventory
+	}
+	return c
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,53 @@
+package history
+
+import (
+	"artifactsmmo/internal/events"
+	"time"
+)
+
+// Average is the observed return of a single action for an activity
+type Average struct {
+	Actions         int
+	XpPerAction     float64
+	GoldPerAction   float64
+	CooldownSeconds float64
+}
+
+// Averages summarises the character's actions since the given time by activity, e.g. "fight chicken"
+func (s *Store) Averages(character string, since time.Time) (map[string]Average, error) {
+	records, err := s.Query(character, since, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	type total struct {
+		actions, xp, gold, cooldown int
+	}
+	totals := map[string]*total{}
+	for _, r := range records {
+		if r.Action != (events.FightFinished{}).Name() && r.Action != (events.ItemGathered{}).Name() {
+			continue
+		}
+		t, ok := totals[r.Request]
+		if !ok {
+			t = &total{}
+			totals[r.Request] = t
+		}
+		t.actions++
+		t.xp += r.Xp
+		t.gold += r.Gold
+		t.cooldown += r.Cooldown
+	}
+
+	res := make(map[string]Average, len(totals))
+	for activity, t := range totals {
+		n := float64(t.actions)
+		res[activity] = Average{
+			Actions:         t.actions,
+			XpPerAction:     float64(t.xp) / n,
+			GoldPerAction:   float64(t.gold) / n,
+			CooldownSeconds: float64(t.cooldown) / n,
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"context"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+)
+
+// Record subscribes to action events on the bus and stores them until the context is cancelled
+func (s *Store) Record(ctx context.Context, bus *events.Bus) {
+	logger := slog.Default().With("source", "history")
+	ch, unsubscribe := bus.Subscribe()
+
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-ch:
+				r, ok := recordFromEvent(ev)
+				if !ok {
+					continue
+				}
+				if err := s.Add(r); err != nil {
+					logger.Warn("storing action", "error", err, "action", r.Action, "character", r.Character)
+				}
+			}
+		}
+	}()
+}
+
+func recordFromEvent(ev events.Event) (Record, bool) {
+	info := ev.Info()
+	r := Record{
+		Time:      info.Time,
+		Character: info.Character,
+		Action:    ev.Name(),
+		Summary:   ev.String(),
+	}
+
+	switch e := ev.(type) {
+	case events.FightFinished:
+		r.Request = commands.FightActivity(e.Monster)
+		r.Skill = models.CombatSkill
+		r.Xp = e.Xp
+		r.Gold = e.Gold
+		r.Drops = e.Drops
+		r.Cooldown = e.Cooldown
+	case events.ItemGathered:
+		r.Request = commands.GatherActivity(e.Resource)
+		r.Skill = e.Skill
+		r.Xp = e.Xp
+		r.Drops = e.Items
+		r.Cooldown = e.Cooldown
+	case events.TaskAccepted:
+		r.Request = "accept task"
+	case events.TaskCoinsExchanged:
+		r.Request = "exchange task coins"
+		r.Drops = []client.DropSchema{{Code: e.Reward.Code, Quantity: e.Reward.Quantity}}
+	case events.TaskCancelled:
+		r.Request = "cancel task"
+	case events.TaskCompleted:
+		r.Request = "complete task"
+		r.Drops = []client.DropSchema{{Code: e.Reward.Code, Quantity: e.Reward.Quantity}}
+	case events.LevelUp:
+		r.Skill = e.Skill
+	case events.StepPlanned:
+		r.Request = e.Activity
+		r.Predicted = &Prediction{XpPerHour: e.XpPerHour, GoldPerHour: e.GoldPerHour}
+	default:
+		return Record{}, false
+	}
+	return r, true
+}
@@ -0,0 +1,111 @@
+package history
+
+import (
+	"artifactsmmo/internal/clock"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+// Record is a single action result, stored per character ordered by time
+type Record struct {
+	Time      time.Time           `json:"time"`
+	Character string              `json:"character"`
+	Action    string              `json:"action"`
+	Request   string              `json:"request"`
+	Skill     string              `json:"skill,omitempty"`
+	Summary   string              `json:"summary"`
+	Xp        int                 `json:"xp"`
+	Gold      int                 `json:"gold"`
+	Drops     []client.DropSchema `json:"drops,omitempty"`
+	Cooldown  int                 `json:"cooldown"`
+	// Predicted is only set on step_planned records
+	Predicted *Prediction `json:"predicted,omitempty"`
+}
+
+// Prediction is what the engine expected a step to return when it was planned, zero when it made no prediction
+type Prediction struct {
+	XpPerHour   float64 `json:"xp_per_hour"`
+	GoldPerHour float64 `json:"gold_per_hour"`
+}
+
+// Store persists records in a bbolt file with a bucket per character, keys are the record time followed by a sequence
+type Store struct {
+	db    *bbolt.DB
+	clock clock.Clock
+}
+
+// Open opens or creates the history file, the clock bounds queries that run up to now
+func Open(path string, clk clock.Clock) (*Store, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open history %s: %w", path, err)
+	}
+	return &Store{db: db, clock: clk}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) Add(r Record) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(r.Character))
+		if err != nil {
+			return fmt.Errorf("create bucket %s: %w", r.Character, err)
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(recordKey(r.Time, seq), value)
+	})
+}
+
+// Query returns the character's records in [from, to) ordered by time
+func (s *Store) Query(character string, from, to time.Time) ([]Record, error) {
+	records := make([]Record, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(character))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		end := recordKey(to, 0)
+		for k, v := c.Seek(recordKey(from, 0)); k != nil && string(k) < string(end); k, v = c.Next() {
+			var r Record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("decode record: %w", err)
+			}
+			records = append(records, r)
+		}
+		return nil
+	})
+	return records, err
+}
+
+func (s *Store) Characters() ([]string, error) {
+	names := make([]string, 0)
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			names = append(names, string(name))
+			return nil
+		})
+	})
+	return names, err
+}
+
+func recordKey(t time.Time, seq uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(k[8:], seq)
+	return k
+}
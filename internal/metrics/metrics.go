@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"artifactsmmo/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"strconv"
+	"time"
+)
+
+const namespace = "artifactsmmo"
+
+var (
+	actions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "actions_total",
+		Help:      "Character actions by result code.",
+	}, []string{"character", "action", "code"})
+
+	apiLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of game api requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	rateLimitWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time requests waited on the client side rate limiter.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 2, 5, 10},
+	}, []string{"budget"})
+
+	cooldown = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "cooldown_seconds",
+		Help:      "Cooldown returned after character actions.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120},
+	}, []string{"character"})
+
+	hp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "character_hp",
+		Help:      "Current character hp.",
+	}, []string{"character"})
+
+	gold = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "character_gold",
+		Help:      "Gold carried by the character.",
+	}, []string{"character"})
+
+	skillLevel = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "skill_level",
+		Help:      "Character level per skill, combat is the character level.",
+	}, []string{"character", "skill"})
+
+	skillXp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "skill_xp",
+		Help:      "Character xp towards the next level per skill, combat is the character xp.",
+	}, []string{"character", "skill"})
+
+	inventoryUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inventory_used",
+		Help:      "Number of items in the character inventory.",
+	}, []string{"character"})
+
+	inventoryMax = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "inventory_max",
+		Help:      "Inventory capacity of the character.",
+	}, []string{"character"})
+
+	bankGold = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "bank_gold",
+		Help:      "Gold stored in the bank of the account.",
+	}, []string{"account"})
+)
+
+func ObserveAction(character, action string, code int) {
+	actions.WithLabelValues(character, action, strconv.Itoa(code)).Inc()
+}
+
+func ObserveAPIRequest(method, route string, code int, d time.Duration) {
+	apiLatency.WithLabelValues(method, route, strconv.Itoa(code)).Observe(d.Seconds())
+}
+
+func ObserveRateLimitWait(budget string, d time.Duration) {
+	rateLimitWait.WithLabelValues(budget).Observe(d.Seconds())
+}
+
+func ObserveCooldown(character string, seconds int) {
+	cooldown.WithLabelValues(character).Observe(float64(seconds))
+}
+
+func setCharacterState(s client.CharacterSchema) {
+	character := s.Name
+	hp.WithLabelValues(character).Set(float64(s.Hp))
+	gold.WithLabelValues(character).Set(float64(s.Gold))
+	skillLevel.WithLabelValues(character, models.CombatSkill).Set(float64(s.Level))
+	skillXp.WithLabelValues(character, models.CombatSkill).Set(float64(s.Xp))
+
+	levels := models.SkillLevels(s)
+	for skill, xp := range models.SkillXp(s) {
+		skillLevel.WithLabelValues(character, skill).Set(float64(levels[skill]))
+		skillXp.WithLabelValues(character, skill).Set(float64(xp))
+	}
+
+	used := 0
+	if s.Inventory != nil {
+		for _, i := range *s.Inventory {
+			used += i.Quantity
+		}
+	}
+	inventoryUsed.WithLabelValues(character).Set(float64(used))
+	inventoryMax.WithLabelValues(character).Set(float64(s.InventoryMaxItems))
+}
+
+func setBankGold(account string, g int) {
+	bankGold.WithLabelValues(account).Set(float64(g))
+}
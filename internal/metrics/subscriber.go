@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"artifactsmmo/internal/events"
+	"context"
+)
+
+// Subscribe keeps the character and bank gauges up to date from the event bus
+func Subscribe(ctx context.Context, bus *events.Bus) {
+	events.Handle(ctx, bus, func(e events.CharacterUpdated) {
+		setCharacterState(e.Schema)
+	})
+	events.Handle(ctx, bus, func(e events.BankChanged) {
+		if e.Gold != nil {
+			setBankGold(e.Account, *e.Gold)
+		}
+	})
+}
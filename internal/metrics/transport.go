@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transport records latency for every request made to the game api
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	ObserveAPIRequest(req.Method, Route(req.URL.Path), code, time.Since(start))
+
+	return resp, err
+}
+
+// Route replaces character names in the path so labels stay bounded, e.g. /my/{name}/action/move
+func Route(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "my" && parts[2] == "action" {
+		parts[1] = "{name}"
+	}
+	if len(parts) >= 2 && parts[0] == "characters" && parts[1] != "create" && parts[1] != "delete" {
+		parts[1] = "{name}"
+	}
+	return "/" + strings.Join(parts, "/")
+}
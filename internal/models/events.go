@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// GameEvent is a timed event that places a monster or resource on a tile until it expires
+type GameEvent struct {
+	Name       string
+	Tile       MapTile
+	Expiration time.Time
+}
+
+func (e GameEvent) Active(now time.Time) bool {
+	return now.Before(e.Expiration)
+}
@@ -1,7 +1,5 @@
 package models
 
-import "github.com/promiseofcake/artifactsmmo-go-client/client"
-
 // todo: this is hacky but temporarily fixing import cycles
 
 const (
@@ -23,14 +21,19 @@ const (
 	Fire  AttackType = "fire"
 )
 
-type BankResponse struct {
-	Gold  *int
-	Items *[]client.SimpleItemSchema
-}
-
 type MapTile struct {
 	X    int
 	Y    int
 	Type string
 	Code string
 }
+
+// TileID identifies a map tile by position, it stays the same when the map content is refreshed
+type TileID struct {
+	X int
+	Y int
+}
+
+func (t MapTile) ID() TileID {
+	return TileID{X: t.X, Y: t.Y}
+}
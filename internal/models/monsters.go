@@ -2,6 +2,8 @@ package models
 
 import (
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"maps"
+	"slices"
 )
 
 type Monster struct {
@@ -51,3 +53,10 @@ func MonsterFromSchema(monster client.MonsterSchema) Monster {
 
 	return m
 }
+
+// Clone copies the resistances and drops, so the copy can be changed without touching the original
+func (m Monster) Clone() Monster {
+	m.Resistances = maps.Clone(m.Resistances)
+	m.Drops = slices.Clone(m.Drops)
+	return m
+}
@@ -0,0 +1,30 @@
+package models
+
+import "github.com/promiseofcake/artifactsmmo-go-client/client"
+
+// CombatSkill is used where the character level is treated like a skill
+const CombatSkill = "combat"
+
+func SkillLevels(s client.CharacterSchema) map[string]int {
+	return map[string]int{
+		WoodcuttingSkill:      s.WoodcuttingLevel,
+		FishingSkill:          s.FishingLevel,
+		MiningSkill:           s.MiningLevel,
+		GearcraftingSkill:     s.GearcraftingLevel,
+		CookingSkill:          s.CookingLevel,
+		WeaponCraftingSkill:   s.WeaponcraftingLevel,
+		JeweleryCraftingSkill: s.JewelrycraftingLevel,
+	}
+}
+
+func SkillXp(s client.CharacterSchema) map[string]int {
+	return map[string]int{
+		WoodcuttingSkill:      s.WoodcuttingXp,
+		FishingSkill:          s.FishingXp,
+		MiningSkill:           s.MiningXp,
+		GearcraftingSkill:     s.GearcraftingXp,
+		CookingSkill:          s.CookingXp,
+		WeaponCraftingSkill:   s.WeaponcraftingXp,
+		JeweleryCraftingSkill: s.JewelrycraftingXp,
+	}
+}
@@ -1,11 +1,16 @@
 package player
 
 import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
 	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/metrics"
 	"artifactsmmo/internal/models"
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"sync"
 	"time"
@@ -24,15 +29,16 @@ func (e PlayerNotFound) Error() string {
 
 type Player struct {
 	Name        string
+	Account     string
 	data        PlayerData
 	mu          sync.RWMutex
 	ctx         context.Context
-	client      *client.ClientWithResponses
-	engineChan  chan commands.CommandResponse
+	client      api.CharacterAPI
+	bus         *events.Bus
+	clock       clock.Clock
 	In          chan commands.Command
 	logger      *slog.Logger
-	bankChannel chan models.BankResponse
-	errChan     chan error
+	currentStep string
 }
 
 type PlayerPosition struct {
@@ -56,8 +62,13 @@ type PlayerData struct {
 	MaxInventory int
 	Inventory    []client.InventorySlot
 	Level        int
+	Xp           int
+	Gold         int
+	SkillXp      map[string]int
 	AttackStats  map[models.AttackType]int
 	DefenseStats map[models.AttackType]int
+	// CooldownExpiration is when the character can act again
+	CooldownExpiration time.Time
 }
 
 type playerResponse struct {
@@ -66,17 +77,17 @@ type playerResponse struct {
 }
 
 // Player is the character abstraction from the engine.
-func NewPlayer(ctx context.Context, name string, client *client.ClientWithResponses, rc chan commands.CommandResponse, bc chan models.BankResponse, errChan chan error) *Player {
+func NewPlayer(ctx context.Context, name, account string, client api.CharacterAPI, bus *events.Bus, clk clock.Clock) *Player {
 	logger := slog.Default().With("source", name)
 	p := &Player{
-		Name:        name,
-		client:      client,
-		ctx:         ctx,
-		engineChan:  rc,
-		In:          make(chan commands.Command),
-		logger:      logger,
-		bankChannel: bc,
-		errChan:     errChan,
+		Name:    name,
+		Account: account,
+		client:  client,
+		ctx:     ctx,
+		bus:     bus,
+		clock:   clk,
+		In:      make(chan commands.Command),
+		logger:  logger,
 	}
 
 	go p.start()
@@ -89,16 +100,17 @@ func (p *Player) start() {
 		var playerNotFound PlayerNotFound
 		if errors.As(err, &playerNotFound) {
 			if pErr := p.createCharacter(); pErr != nil {
-				p.errChan <- fmt.Errorf("error creating character: %s", pErr)
+				p.publishError(fmt.Errorf("error creating character: %s", pErr))
+				return
 			}
 		} else {
-			p.errChan <- fmt.Errorf("error getting data in character %s: %s", p.Name, err)
+			p.publishError(fmt.Errorf("error getting data in character %s: %s", p.Name, err))
 			return
 		}
 	}
 
 	//send initial command to tell eng online
-	p.engineChan <- commands.CommandResponse{Name: p.Name, Code: commands.PlayerStartedCode}
+	p.bus.Publish(events.CommandFinished{Meta: events.NewMeta(p.Name, p.clock), Code: commands.PlayerStartedCode})
 
 	for {
 		select {
@@ -106,18 +118,21 @@ func (p *Player) start() {
 			return
 		case cmd := <-p.In:
 			r := p.processCommand(cmd)
-			p.engineChan <- commands.CommandResponse{Name: p.Name, Error: r.Error, Code: r.Code}
-		default:
-			//loop
+			p.bus.Publish(events.CommandFinished{Meta: events.NewMeta(p.Name, p.clock), Error: r.Error, Code: r.Code})
 		}
 	}
 }
 
 func (p *Player) processCommand(cmd commands.Command) *playerResponse {
+	defer p.setCurrentStep("")
+	code := http.StatusOK
 	for _, s := range cmd.Steps {
+		p.setCurrentStep(s.String())
 	loop:
 		for {
-			if code, err := s.Execute(p); err != nil {
+			var err error
+			//non 200 codes go back to the engine so it can decide what to do, e.g. deposit on 497
+			if code, err = s.Execute(p); err != nil || code != http.StatusOK {
 				return &playerResponse{
 					Code:  code,
 					Error: err,
@@ -128,7 +143,20 @@ func (p *Player) processCommand(cmd commands.Command) *playerResponse {
 			}
 		}
 	}
-	return nil
+	return &playerResponse{Code: code}
+}
+
+// CurrentStep describes the step the player is working on, empty when idle
+func (p *Player) CurrentStep() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.currentStep
+}
+
+func (p *Player) setCurrentStep(step string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.currentStep = step
 }
 
 func (p *Player) Data() PlayerData {
@@ -143,19 +171,23 @@ func (p *Player) move(x, y int) int {
 		return 200
 	}
 	p.logger.Debug("moving character to position")
+	sent := p.clock.Now()
 	resp, err := p.client.ActionMoveMyNameActionMovePostWithResponse(p.ctx, p.Name, client.ActionMoveMyNameActionMovePostJSONRequestBody{
 		X: x,
 		Y: y,
 	})
 
 	if err != nil {
-		p.logger.Debug("error moving character to position: %v\n", err)
-		return resp.StatusCode()
+		p.logger.Debug("error moving character to position", "error", err)
+		return p.reconcile("move", sent, 0, err, atTile(x, y))
 	}
+	metrics.ObserveAction(p.Name, "move", resp.StatusCode())
 
-	if resp.StatusCode() == 200 {
-		p.UpdateData(resp.JSON200.Data.Character)
+	if resp.StatusCode() != http.StatusOK {
+		//the cached position may be wrong, e.g. 490 when the character already is on the tile
+		return p.reconcile("move", sent, resp.StatusCode(), nil, atTile(x, y))
 	}
+	p.UpdateData(resp.JSON200.Data.Character)
 
 	return resp.StatusCode()
 }
@@ -167,19 +199,42 @@ func (p *Player) Gather(tile models.MapTile) int {
 	}
 
 	p.logger.Debug("gathering", "resource", tile.Code)
+	sent := p.clock.Now()
 	resp, err := p.client.ActionGatheringMyNameActionGatheringPostWithResponse(p.ctx, p.Name)
 	if err != nil {
-		p.logger.Debug("error gathering characters: %v", err)
-		return resp.StatusCode()
+		p.logger.Debug("error gathering", "error", err)
+		return p.reconcile("gather", sent, 0, err, gainedXp(sent))
 	}
+	metrics.ObserveAction(p.Name, "gather", resp.StatusCode())
 
-	if resp.StatusCode() == http.StatusOK {
-		p.UpdateData(resp.JSON200.Data.Character)
+	if resp.StatusCode() != http.StatusOK {
+		return p.reconcile("gather", sent, resp.StatusCode(), nil, gainedXp(sent))
 	}
+	p.bus.Publish(events.ItemGathered{
+		Meta:     events.NewMeta(p.Name, p.clock),
+		Resource: tile.Code,
+		Skill:    p.gatheredSkill(resp.JSON200.Data.Character),
+		Xp:       resp.JSON200.Data.Details.Xp,
+		Items:    resp.JSON200.Data.Details.Items,
+		Cooldown: resp.JSON200.Data.Cooldown.TotalSeconds,
+	})
+	p.UpdateData(resp.JSON200.Data.Character)
 
 	return resp.StatusCode()
 }
 
+// gatheredSkill finds the skill that gained xp or a level compared to the current data
+func (p *Player) gatheredSkill(s client.CharacterSchema) string {
+	previous := p.Data()
+	levels := models.SkillLevels(s)
+	for skill, xp := range models.SkillXp(s) {
+		if xp != previous.SkillXp[skill] || levels[skill] != previous.Skills[skill] {
+			return skill
+		}
+	}
+	return ""
+}
+
 func (p *Player) Pos() (x, y int) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -187,14 +242,11 @@ func (p *Player) Pos() (x, y int) {
 }
 
 func (p *Player) getData() error {
-	resp, err := p.client.GetCharacterCharactersNameGetWithResponse(p.ctx, p.Name)
+	s, err := p.fetchCharacter()
 	if err != nil {
-		return fmt.Errorf("get character status: %w", err)
-	}
-	if resp.StatusCode() == http.StatusNotFound {
-		return PlayerNotFound{}
+		return err
 	}
-	p.UpdateData(resp.JSON200.Data)
+	p.UpdateData(s)
 
 	return nil
 }
@@ -205,43 +257,94 @@ func (p *Player) DepositInventory(tile models.MapTile) int {
 		return code
 	}
 	p.logger.Debug("depositing inventory")
-	var code int
 	for _, i := range p.Data().Inventory {
-		if i.Quantity > 0 {
-			code = p.depositItem(i.Code, i.Quantity)
+		if i.Quantity <= 0 {
+			continue
+		}
+		//stop at the first failure, the engine plans again from what is left
+		if code := p.depositItem(i.Code, i.Quantity); code != http.StatusOK {
+			p.logger.Warn("Could not deposit inventory", "item", i.Code, slog.Group("code", code))
+			return code
 		}
 	}
 	p.logger.Debug("depositing inventory complete")
-	return code
+	return http.StatusOK
 }
 
 // depositItem is meant to be called when the player is already at the bank, If a use case comes up where the player needs to deposit a single item we will need to refactor
 func (p *Player) depositItem(code string, qty int) int {
+	sent := p.clock.Now()
 	resp, err := p.client.ActionDepositBankMyNameActionBankDepositPostWithResponse(p.ctx, p.Name, client.ActionDepositBankMyNameActionBankDepositPostJSONRequestBody{
 		Code:     code,
 		Quantity: qty,
 	})
 	if err != nil {
-		p.logger.Debug("deposit inventory: %v", err)
-		return resp.StatusCode()
+		p.logger.Debug("deposit inventory", "error", err)
+		return p.reconcile("deposit", sent, 0, err, itemMoved(sent, code, false))
 	}
+	metrics.ObserveAction(p.Name, "deposit", resp.StatusCode())
 
-	if resp.HTTPResponse.StatusCode == 200 {
-		p.bankChannel <- models.BankResponse{
-			Gold:  nil,
-			Items: &resp.JSON200.Data.Bank,
-		}
-		p.UpdateData(resp.JSON200.Data.Character)
-
+	if resp.StatusCode() != http.StatusOK {
+		return p.reconcile("deposit", sent, resp.StatusCode(), nil, itemMoved(sent, code, false))
 	}
+	p.bus.Publish(events.BankChanged{
+		Meta:    events.NewMeta(p.Name, p.clock),
+		Account: p.Account,
+		Items:   &resp.JSON200.Data.Bank,
+	})
+	p.UpdateData(resp.JSON200.Data.Character)
 
 	p.logger.Debug("deposit complete")
 	return resp.StatusCode()
 }
 
+// WithdrawItem moves to the bank and takes qty of the item into the inventory
+func (p *Player) WithdrawItem(tile models.MapTile, code string, qty int) int {
+	if c := p.move(tile.X, tile.Y); c != http.StatusOK {
+		p.logger.Warn("Could not move to withdraw", slog.Group("code", c))
+		return c
+	}
+	sent := p.clock.Now()
+	resp, err := p.client.ActionWithdrawBankMyNameActionBankWithdrawPostWithResponse(p.ctx, p.Name, client.ActionWithdrawBankMyNameActionBankWithdrawPostJSONRequestBody{
+		Code:     code,
+		Quantity: qty,
+	})
+	if err != nil {
+		p.logger.Debug("withdraw item", "error", err)
+		return p.reconcile("withdraw", sent, 0, err, itemMoved(sent, code, true))
+	}
+	metrics.ObserveAction(p.Name, "withdraw", resp.StatusCode())
+
+	if resp.StatusCode() != http.StatusOK {
+		return p.reconcile("withdraw", sent, resp.StatusCode(), nil, itemMoved(sent, code, true))
+	}
+	p.bus.Publish(events.BankChanged{
+		Meta:    events.NewMeta(p.Name, p.clock),
+		Account: p.Account,
+		Items:   &resp.JSON200.Data.Bank,
+	})
+	p.UpdateData(resp.JSON200.Data.Character)
+
+	return resp.StatusCode()
+}
+
 // UpdateData updates the player data and wait for the cooldown
 func (p *Player) UpdateData(s client.CharacterSchema) {
+	p.setData(s, p.clock.Now().Add(time.Duration(s.Cooldown)*time.Second))
+	metrics.ObserveCooldown(p.Name, s.Cooldown)
+
+	//temporary while we cant use expiration for fighting due to early timeout
+	// if cd, err := s.CooldownExpiration.AsCharacterSchemaCooldownExpiration0(); err != nil {
+	// 	waitForCooldownSeconds(s.Cooldown)
+	// } else if cd.After(time.Now()) {
+	p.waitForCooldownSeconds(s.Cooldown)
+	// }
+}
+
+// setData stores the character and publishes what changed, expiration is when it can act again
+func (p *Player) setData(s client.CharacterSchema, expiration time.Time) {
 	p.mu.Lock()
+	previous := p.data
 
 	var task *PlayerTask
 
@@ -265,15 +368,10 @@ func (p *Player) UpdateData(s client.CharacterSchema) {
 			X: s.X,
 			Y: s.Y,
 		},
-		Skills: map[string]int{
-			models.WoodcuttingSkill:      s.WoodcuttingLevel,
-			models.FishingSkill:          s.FishingLevel,
-			models.MiningSkill:           s.MiningLevel,
-			models.GearcraftingSkill:     s.GearcraftingLevel,
-			models.CookingSkill:          s.CookingLevel,
-			models.WeaponCraftingSkill:   s.WeaponcraftingLevel,
-			models.JeweleryCraftingSkill: s.JewelrycraftingLevel,
-		},
+		Gold:    s.Gold,
+		Xp:      s.Xp,
+		Skills:  models.SkillLevels(s),
+		SkillXp: models.SkillXp(s),
 		AttackStats: map[models.AttackType]int{
 			models.Fire:  calculateElementDamage(s.AttackFire, s.DmgFire),
 			models.Air:   calculateElementDamage(s.AttackAir, s.DmgAir),
@@ -286,15 +384,32 @@ func (p *Player) UpdateData(s client.CharacterSchema) {
 			models.Water: s.ResWater,
 			models.Earth: s.ResEarth,
 		},
+		CooldownExpiration: expiration,
 	}
+	data := p.data
 	p.mu.Unlock()
 
-	//temporary while we cant use expiration for fighting due to early timeout
-	// if cd, err := s.CooldownExpiration.AsCharacterSchemaCooldownExpiration0(); err != nil {
-	// 	waitForCooldownSeconds(s.Cooldown)
-	// } else if cd.After(time.Now()) {
-	waitForCooldownSeconds(s.Cooldown)
-	// }
+	p.bus.Publish(events.CharacterUpdated{Meta: events.NewMeta(p.Name, p.clock), Schema: s})
+	p.publishLevelUps(previous, data)
+}
+
+// publishLevelUps compares skill levels between updates, nothing is published for the first load
+func (p *Player) publishLevelUps(previous, current PlayerData) {
+	if previous.Skills == nil {
+		return
+	}
+	if current.Level > previous.Level {
+		p.bus.Publish(events.LevelUp{Meta: events.NewMeta(p.Name, p.clock), Skill: models.CombatSkill, Level: current.Level})
+	}
+	for skill, level := range current.Skills {
+		if level > previous.Skills[skill] {
+			p.bus.Publish(events.LevelUp{Meta: events.NewMeta(p.Name, p.clock), Skill: skill, Level: level})
+		}
+	}
+}
+
+func (p *Player) publishError(err error) {
+	p.bus.Publish(events.ErrorOccurred{Meta: events.NewMeta(p.Name, p.clock), Err: err})
 }
 
 func (p *Player) InventoryCapacity() int {
@@ -314,40 +429,64 @@ func (p *Player) Fight(tile models.MapTile) (bool, int) {
 		p.logger.Warn("Could not move to fight", slog.Group("code", code))
 		return false, code
 	}
+	sent := p.clock.Now()
 	resp, err := p.client.ActionFightMyNameActionFightPostWithResponse(p.ctx, p.Name)
 	if err != nil {
 		p.logger.Debug("fight error", "error", err)
+		//only a won fight gives xp, so an applied fight is a win
+		code := p.reconcile("fight", sent, 0, err, gainedXp(sent))
+		return code == http.StatusOK, code
 	}
+	metrics.ObserveAction(p.Name, "fight", resp.StatusCode())
 	if resp.StatusCode() != 200 {
 		p.logger.Debug("got non 200 status from fight", "code", resp.StatusCode())
-		return false, resp.StatusCode()
+		code := p.reconcile("fight", sent, resp.StatusCode(), nil, gainedXp(sent))
+		return code == http.StatusOK, code
 	}
 
-	p.logger.Debug("fight result", "result", resp.JSON200.Data.Fight.Result, "turns", resp.JSON200.Data.Fight.Turns, "monster", tile.Code)
+	fight := resp.JSON200.Data.Fight
+	p.logger.Debug("fight result", "result", fight.Result, "turns", fight.Turns, "monster", tile.Code)
+	p.bus.Publish(events.FightFinished{
+		Meta:     events.NewMeta(p.Name, p.clock),
+		Monster:  tile.Code,
+		Win:      fight.Result == "win",
+		Turns:    fight.Turns,
+		Xp:       fight.Xp,
+		Gold:     fight.Gold,
+		Drops:    fight.Drops,
+		Cooldown: resp.JSON200.Data.Cooldown.TotalSeconds,
+	})
 	p.UpdateData(resp.JSON200.Data.Character)
 
 	return resp.JSON200.Data.Fight.Result == "win", resp.StatusCode()
 }
 
 func (p *Player) CanWinFight(attackType models.AttackType, monster models.Monster) bool {
+	win, _ := p.EstimateFight(monster)
+	return win
+}
+
+// EstimateFight predicts if the player beats the monster and how many turns the player needs to do it
+func (p *Player) EstimateFight(monster models.Monster) (bool, int) {
+	data := p.Data()
 	// Calculate the damage each entity deals
-	monsterDmg := calculateAttackDamage(monster.AttackDmg, p.Data().DefenseStats[monster.AttackType])
+	monsterDmg := calculateAttackDamage(monster.AttackDmg, data.DefenseStats[monster.AttackType])
 
 	//figure out all the damage we do across all the types
 	playerDmg := 0
-	for dmgType, attack := range p.Data().AttackStats {
+	for dmgType, attack := range data.AttackStats {
 		playerDmg += calculateAttackDamage(attack, monster.Resistances[dmgType])
 	}
 
 	// Calculate how many turns each entity can take
 	playerTurns := float64(monster.Hp) / float64(playerDmg)
-	monsterTurns := float64(p.Data().Hp) / float64(monsterDmg)
+	monsterTurns := float64(data.Hp) / float64(monsterDmg)
 
 	// Determine the outcome
 	if playerTurns >= float64(maxFightRounds) || playerTurns >= monsterTurns {
-		return false
+		return false, maxFightRounds
 	}
-	return true
+	return true, int(math.Ceil(playerTurns))
 }
 
 func (p *Player) CheckInventory(code string) int {
@@ -362,10 +501,18 @@ func (p *Player) CheckInventory(code string) int {
 	return count
 }
 
-func waitForCooldown(expiration time.Time) {
-	time.Sleep(time.Until(expiration))
+func (p *Player) waitForCooldown(expiration time.Time) {
+	p.wait(expiration.Sub(p.clock.Now()))
 }
 
-func waitForCooldownSeconds(seconds int) {
-	time.Sleep(time.Duration(seconds) * time.Second)
+func (p *Player) waitForCooldownSeconds(seconds int) {
+	p.wait(time.Duration(seconds) * time.Second)
+}
+
+// wait sleeps on the player's clock, returning early when the game stops
+func (p *Player) wait(d time.Duration) {
+	select {
+	case <-p.ctx.Done():
+	case <-p.clock.After(d):
+	}
 }
@@ -0,0 +1,323 @@
+package player
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+)
+
+// stubCharacter plays the api for one character without http, fail holds the code an action answers instead of 200
+// and applied whether a failed action still went through, like a timeout after the server acted
+type stubCharacter struct {
+	api.CharacterAPI
+	clock   *clock.Fake
+	char    client.CharacterSchema
+	fail    map[string]int
+	applied bool
+	calls   []string
+}
+
+func status(code int) *http.Response {
+	return &http.Response{StatusCode: code}
+}
+
+// do records the call and applies the action unless it failed without going through
+func (s *stubCharacter) do(action string, apply func()) int {
+	s.calls = append(s.calls, action)
+	code := http.StatusOK
+	if f, ok := s.fail[action]; ok {
+		code = f
+	}
+	if code == http.StatusOK || s.applied {
+		apply()
+		expiration := s.clock.Now()
+		s.char.CooldownExpiration = &expiration
+	}
+	return code
+}
+
+func (s *stubCharacter) GetCharacterCharactersNameGetWithResponse(_ context.Context, _ string, _ ...client.RequestEditorFn) (*client.GetCharacterCharactersNameGetResponse, error) {
+	s.calls = append(s.calls, "get")
+	return &client.GetCharacterCharactersNameGetResponse{HTTPResponse: status(http.StatusOK), JSON200: &client.CharacterResponseSchema{Data: s.char}}, nil
+}
+
+func (s *stubCharacter) ActionMoveMyNameActionMovePostWithResponse(_ context.Context, _ string, body client.ActionMoveMyNameActionMovePostJSONRequestBody, _ ...client.RequestEditorFn) (*client.ActionMoveMyNameActionMovePostResponse, error) {
+	code := s.do("move", func() {
+		s.char.X, s.char.Y = body.X, body.Y
+	})
+	resp := &client.ActionMoveMyNameActionMovePostResponse{HTTPResponse: status(code)}
+	if code == http.StatusOK {
+		resp.JSON200 = &client.CharacterMovementResponseSchema{Data: client.CharacterMovementDataSchema{Character: s.char}}
+	}
+	return resp, nil
+}
+
+func (s *stubCharacter) ActionGatheringMyNameActionGatheringPostWithResponse(_ context.Context, _ string, _ ...client.RequestEditorFn) (*client.ActionGatheringMyNameActionGatheringPostResponse, error) {
+	drop := client.DropSchema{Code: "copper_ore", Quantity: 1}
+	code := s.do("gather", func() {
+		s.char.MiningXp += 10
+		s.addItem(drop.Code, drop.Quantity)
+	})
+	resp := &client.ActionGatheringMyNameActionGatheringPostResponse{HTTPResponse: status(code)}
+	if code == http.StatusOK {
+		resp.JSON200 = &client.SkillResponseSchema{Data: client.SkillDataSchema{
+			Character: s.char,
+			Details:   client.SkillInfoSchema{Xp: 10, Items: []client.DropSchema{drop}},
+		}}
+	}
+	return resp, nil
+}
+
+func (s *stubCharacter) ActionDepositBankMyNameActionBankDepositPostWithResponse(_ context.Context, _ string, body client.ActionDepositBankMyNameActionBankDepositPostJSONRequestBody, _ ...client.RequestEditorFn) (*client.ActionDepositBankMyNameActionBankDepositPostResponse, error) {
+	code := s.do("deposit "+body.Code, func() {
+		s.addItem(body.Code, -body.Quantity)
+	})
+	resp := &client.ActionDepositBankMyNameActionBankDepositPostResponse{HTTPResponse: status(code)}
+	if code == http.StatusOK {
+		resp.JSON200 = &client.BankItemTransactionResponseSchema{Data: client.BankItemTransactionSchema{
+			Bank:      []client.SimpleItemSchema{{Code: body.Code, Quantity: body.Quantity}},
+			Character: s.char,
+		}}
+	}
+	return resp, nil
+}
+
+func (s *stubCharacter) addItem(code string, qty int) {
+	inventory := slices.Clone(*s.char.Inventory)
+	for i := range inventory {
+		if inventory[i].Code == code {
+			inventory[i].Quantity += qty
+			if inventory[i].Quantity == 0 {
+				inventory[i].Code = ""
+			}
+			s.char.Inventory = &inventory
+			return
+		}
+	}
+	for i := range inventory {
+		if inventory[i].Code == "" {
+			inventory[i] = client.InventorySlot{Slot: i + 1, Code: code, Quantity: qty}
+			break
+		}
+	}
+	s.char.Inventory = &inventory
+}
+
+func character(x, y int, items ...client.InventorySlot) client.CharacterSchema {
+	inventory := make([]client.InventorySlot, 4)
+	for i := range inventory {
+		inventory[i].Slot = i + 1
+	}
+	copy(inventory, items)
+	return client.CharacterSchema{Name: "alice", X: x, Y: y, Level: 1, MiningLevel: 1, InventoryMaxItems: 100, Inventory: &inventory}
+}
+
+// newTestPlayer builds a player on the stub without starting its command loop
+func newTestPlayer(t *testing.T, stub *stubCharacter) *Player {
+	t.Helper()
+	stub.clock = clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := &Player{
+		Name:   "alice",
+		ctx:    context.Background(),
+		client: stub,
+		bus:    events.NewBus(),
+		clock:  stub.clock,
+		logger: slog.Default(),
+	}
+	p.setData(stub.char, stub.clock.Now())
+	stub.calls = nil
+	return p
+}
+
+func TestGather(t *testing.T) {
+	rocks := models.MapTile{X: 2, Y: 0, Type: "resource", Code: "copper_rocks"}
+	tests := []struct {
+		name      string
+		x, y      int
+		fail      map[string]int
+		applied   bool
+		wantCode  int
+		wantCalls []string
+		wantXp    int
+	}{
+		{name: "on the tile", x: 2, wantCode: http.StatusOK, wantCalls: []string{"gather"}, wantXp: 10},
+		{name: "moves first", wantCode: http.StatusOK, wantCalls: []string{"move", "gather"}, wantXp: 10},
+		{name: "move fails", fail: map[string]int{"move": 490}, wantCode: 490, wantCalls: []string{"move", "get"}},
+		{name: "failed but went through", x: 2, fail: map[string]int{"gather": 499}, applied: true, wantCode: http.StatusOK, wantCalls: []string{"gather", "get"}, wantXp: 10},
+		{name: "failed and did not go through", x: 2, fail: map[string]int{"gather": 499}, wantCode: commands.ReconciledCode, wantCalls: []string{"gather", "get"}},
+		{name: "inventory full", x: 2, fail: map[string]int{"gather": 497}, wantCode: 497, wantCalls: []string{"gather", "get"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubCharacter{char: character(tt.x, tt.y), fail: tt.fail, applied: tt.applied}
+			p := newTestPlayer(t, stub)
+
+			if code := p.Gather(rocks); code != tt.wantCode {
+				t.Errorf("code %d, want %d", code, tt.wantCode)
+			}
+			if !slices.Equal(stub.calls, tt.wantCalls) {
+				t.Errorf("calls %v, want %v", stub.calls, tt.wantCalls)
+			}
+			if xp := p.Data().SkillXp[models.MiningSkill]; xp != tt.wantXp {
+				t.Errorf("mining xp %d, want %d", xp, tt.wantXp)
+			}
+		})
+	}
+}
+
+func TestDepositInventory(t *testing.T) {
+	bank := models.MapTile{X: 4, Y: 1, Type: "bank", Code: "bank"}
+	tests := []struct {
+		name          string
+		items         []client.InventorySlot
+		fail          map[string]int
+		applied       bool
+		wantCode      int
+		wantCalls     []string
+		wantInventory int
+	}{
+		{
+			name:      "every item",
+			items:     []client.InventorySlot{{Code: "copper_ore", Quantity: 5}, {Code: "raw_chicken", Quantity: 2}},
+			wantCode:  http.StatusOK,
+			wantCalls: []string{"move", "deposit copper_ore", "deposit raw_chicken"},
+		},
+		{
+			name:      "skips empty slots",
+			items:     []client.InventorySlot{{}, {Code: "copper_ore", Quantity: 5}},
+			wantCode:  http.StatusOK,
+			wantCalls: []string{"move", "deposit copper_ore"},
+		},
+		{
+			name:      "nothing to deposit",
+			items:     []client.InventorySlot{{}},
+			wantCode:  http.StatusOK,
+			wantCalls: []string{"move"},
+		},
+		{
+			name:          "stops at the first failure",
+			items:         []client.InventorySlot{{Code: "copper_ore", Quantity: 5}, {Code: "raw_chicken", Quantity: 2}},
+			fail:          map[string]int{"deposit copper_ore": 478},
+			wantCode:      commands.ReconciledCode,
+			wantCalls:     []string{"move", "deposit copper_ore", "get"},
+			wantInventory: 7,
+		},
+		{
+			name:      "failed but went through",
+			items:     []client.InventorySlot{{Code: "copper_ore", Quantity: 5}},
+			fail:      map[string]int{"deposit copper_ore": 499},
+			applied:   true,
+			wantCode:  http.StatusOK,
+			wantCalls: []string{"move", "deposit copper_ore", "get"},
+		},
+		{
+			name:          "failed and did not go through",
+			items:         []client.InventorySlot{{Code: "copper_ore", Quantity: 5}},
+			fail:          map[string]int{"deposit copper_ore": 499},
+			wantCode:      commands.ReconciledCode,
+			wantCalls:     []string{"move", "deposit copper_ore", "get"},
+			wantInventory: 5,
+		},
+		{
+			name:          "move fails",
+			items:         []client.InventorySlot{{Code: "copper_ore", Quantity: 5}},
+			fail:          map[string]int{"move": 486},
+			wantCode:      commands.ReconciledCode,
+			wantCalls:     []string{"move", "get"},
+			wantInventory: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubCharacter{char: character(0, 0, tt.items...), fail: tt.fail, applied: tt.applied}
+			p := newTestPlayer(t, stub)
+
+			if code := p.DepositInventory(bank); code != tt.wantCode {
+				t.Errorf("code %d, want %d", code, tt.wantCode)
+			}
+			if !slices.Equal(stub.calls, tt.wantCalls) {
+				t.Errorf("calls %v, want %v", stub.calls, tt.wantCalls)
+			}
+			if held := p.Data().MaxInventory - p.InventoryCapacity(); held != tt.wantInventory {
+				t.Errorf("%d items left, want %d", held, tt.wantInventory)
+			}
+		})
+	}
+}
+
+func TestUpdateData(t *testing.T) {
+	withTask := character(0, 0)
+	withTask.Task, withTask.TaskType, withTask.TaskProgress, withTask.TaskTotal = "chicken", "monsters", 3, 10
+	leveled := character(0, 0)
+	leveled.Level, leveled.MiningLevel = 2, 3
+
+	tests := []struct {
+		name         string
+		previous     *client.CharacterSchema
+		current      client.CharacterSchema
+		wantTask     *PlayerTask
+		wantLevelUps []string
+	}{
+		{name: "no task", current: character(0, 0)},
+		{name: "task", current: withTask, wantTask: &PlayerTask{Code: "chicken", Type: "monsters", Progress: 3, Total: 10}},
+		{name: "first load has no level ups", current: leveled},
+		{name: "level ups", previous: ptr(character(0, 0)), current: leveled, wantLevelUps: []string{"combat 2", "mining 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Player{
+				Name:   "alice",
+				ctx:    context.Background(),
+				bus:    events.NewBus(),
+				clock:  clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				logger: slog.Default(),
+			}
+			published, unsubscribe := p.bus.Subscribe()
+			defer unsubscribe()
+
+			if tt.previous != nil {
+				p.UpdateData(*tt.previous)
+			}
+			p.UpdateData(tt.current)
+			//the notice marks the end of what the updates published
+			p.bus.Publish(events.Notice{Meta: events.NewMeta(p.Name, p.clock), Message: "done"})
+
+			levelUps := make([]string, 0)
+			for e := range published {
+				if _, ok := e.(events.Notice); ok {
+					break
+				}
+				if l, ok := e.(events.LevelUp); ok {
+					levelUps = append(levelUps, fmt.Sprintf("%s %d", l.Skill, l.Level))
+				}
+			}
+			slices.Sort(levelUps)
+			if !slices.Equal(levelUps, tt.wantLevelUps) {
+				t.Errorf("level ups %v, want %v", levelUps, tt.wantLevelUps)
+			}
+
+			data := p.Data()
+			if (data.Task == nil) != (tt.wantTask == nil) || (data.Task != nil && *data.Task != *tt.wantTask) {
+				t.Errorf("task %+v, want %+v", data.Task, tt.wantTask)
+			}
+			if data.Level != tt.current.Level || data.Skills[models.MiningSkill] != tt.current.MiningLevel {
+				t.Errorf("level %d mining %d, want %d and %d", data.Level, data.Skills[models.MiningSkill], tt.current.Level, tt.current.MiningLevel)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,155 @@
+package player
+
+import (
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// clockSkew is how far the game's clock may be off ours when telling if a cooldown started after an action was sent
+const clockSkew = time.Second
+
+// staleCodes are game errors that mean the cached character was wrong or busy, e.g. 598 when it is not on the tile it thinks it is
+var staleCodes = map[int]bool{
+	461: true,
+	478: true,
+	486: true,
+	499: true,
+	598: true,
+}
+
+// applied tells from the character before and after a failed action if the action went through anyway
+type applied func(before PlayerData, after client.CharacterSchema) bool
+
+// reconcile re-reads the character after an action failed or got no answer and returns the code the step should see.
+// An action that went through anyway returns 200. A stale state or a lost answer returns commands.ReconciledCode
+// so the engine plans again from the real state, any other game error is passed on.
+func (p *Player) reconcile(action string, sent time.Time, code int, err error, ok applied) int {
+	before := p.Data()
+	after, fetchErr := p.fetchCharacter()
+	if fetchErr != nil {
+		p.logger.Warn("could not re-read character", "action", action, "code", code, "error", err, "fetch_error", fetchErr)
+		if err != nil {
+			return http.StatusServiceUnavailable
+		}
+		return code
+	}
+
+	done := ok(before, after)
+	p.logger.Warn("re-read character after failed action", "action", action, "code", code, "error", err, "applied", done)
+	p.bus.Publish(events.Notice{Meta: events.NewMeta(p.Name, p.clock), Message: fmt.Sprintf("re-read character after %s failed, applied: %t", action, done)})
+
+	expiration := p.clock.Now()
+	if after.CooldownExpiration != nil && after.CooldownExpiration.After(expiration) {
+		expiration = *after.CooldownExpiration
+	}
+	p.setData(after, expiration)
+	p.waitForCooldown(expiration)
+
+	switch {
+	case done:
+		return http.StatusOK
+	case err != nil || code >= http.StatusInternalServerError || staleCodes[code]:
+		return commands.ReconciledCode
+	}
+	return code
+}
+
+func (p *Player) fetchCharacter() (client.CharacterSchema, error) {
+	resp, err := p.client.GetCharacterCharactersNameGetWithResponse(p.ctx, p.Name)
+	if err != nil {
+		return client.CharacterSchema{}, fmt.Errorf("get character %s: %w", p.Name, err)
+	}
+	if resp.StatusCode() == http.StatusNotFound {
+		return client.CharacterSchema{}, PlayerNotFound{}
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return client.CharacterSchema{}, fmt.Errorf("get character %s: %d", p.Name, resp.StatusCode())
+	}
+	return resp.JSON200.Data, nil
+}
+
+// actedSince tells if the character started a cooldown after the time, only an action sent after it can have started it
+func actedSince(sent time.Time, after client.CharacterSchema) bool {
+	if after.CooldownExpiration == nil {
+		return false
+	}
+	started := after.CooldownExpiration.Add(-time.Duration(after.Cooldown) * time.Second)
+	return started.After(sent.Add(-clockSkew))
+}
+
+func atTile(x, y int) applied {
+	return func(_ PlayerData, after client.CharacterSchema) bool {
+		return after.X == x && after.Y == y
+	}
+}
+
+// gainedXp is a gather or a won fight, a lost fight gives no xp
+func gainedXp(sent time.Time) applied {
+	return func(before PlayerData, after client.CharacterSchema) bool {
+		if !actedSince(sent, after) {
+			return false
+		}
+		if after.Xp != before.Xp || after.Level != before.Level {
+			return true
+		}
+		levels := models.SkillLevels(after)
+		for skill, xp := range models.SkillXp(after) {
+			if xp != before.SkillXp[skill] || levels[skill] != before.Skills[skill] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// itemMoved checks the item count went the way of the action, down for deposits and up for withdrawals
+func itemMoved(sent time.Time, code string, up bool) applied {
+	return func(before PlayerData, after client.CharacterSchema) bool {
+		if !actedSince(sent, after) || after.Inventory == nil {
+			return false
+		}
+		was, is := itemCount(before.Inventory, code), itemCount(*after.Inventory, code)
+		if up {
+			return is > was
+		}
+		return is < was
+	}
+}
+
+// taskChanged checks the character has a task after accepting one, or none after completing or cancelling
+func taskChanged(sent time.Time, has bool) applied {
+	return func(_ PlayerData, after client.CharacterSchema) bool {
+		return actedSince(sent, after) && (after.Task != "") == has
+	}
+}
+
+// inventoryChanged is for exchanges, coins go and a reward comes
+func inventoryChanged(sent time.Time) applied {
+	return func(before PlayerData, after client.CharacterSchema) bool {
+		if !actedSince(sent, after) || after.Inventory == nil {
+			return false
+		}
+		for _, slot := range append(slices.Clone(before.Inventory), *after.Inventory...) {
+			if slot.Code != "" && itemCount(before.Inventory, slot.Code) != itemCount(*after.Inventory, slot.Code) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func itemCount(slots []client.InventorySlot, code string) int {
+	count := 0
+	for _, s := range slots {
+		if s.Code == code {
+			count += s.Quantity
+		}
+	}
+	return count
+}
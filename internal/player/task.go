@@ -1,6 +1,8 @@
 package player
 
 import (
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/metrics"
 	"artifactsmmo/internal/models"
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
 	"net/http"
@@ -12,15 +14,18 @@ func (p *Player) AcceptNewTask(tile models.MapTile) int {
 	}
 
 	p.logger.Debug("getting new task")
+	sent := p.clock.Now()
 	resp, err := p.client.ActionAcceptNewTaskMyNameActionTaskNewPostWithResponse(p.ctx, p.Name)
 	if err != nil {
-		panic(err)
+		return p.reconcile("task accept", sent, 0, err, taskChanged(sent, true))
 	}
+	metrics.ObserveAction(p.Name, "task_accept", resp.StatusCode())
 	if resp.StatusCode() != 200 {
-		return resp.StatusCode()
+		return p.reconcile("task accept", sent, resp.StatusCode(), nil, taskChanged(sent, true))
 	}
 
 	p.logger.Info("got new task", "task", resp.JSON200.Data.Task)
+	p.bus.Publish(events.TaskAccepted{Meta: events.NewMeta(p.Name, p.clock), Task: resp.JSON200.Data.Task})
 	p.UpdateData(resp.JSON200.Data.Character)
 
 	return resp.StatusCode()
@@ -31,14 +36,17 @@ func (p *Player) CompleteTask(tile models.MapTile) (*client.TaskRewardSchema, in
 		return nil, code
 	}
 	p.logger.Debug("completing task")
+	sent := p.clock.Now()
 	resp, err := p.client.ActionCompleteTaskMyNameActionTaskCompletePostWithResponse(p.ctx, p.Name)
 	if err != nil {
-		panic(err)
+		return nil, p.reconcile("task complete", sent, 0, err, taskChanged(sent, false))
 	}
+	metrics.ObserveAction(p.Name, "task_complete", resp.StatusCode())
 	if resp.StatusCode() != 200 {
-		return nil, resp.StatusCode()
+		return nil, p.reconcile("task complete", sent, resp.StatusCode(), nil, taskChanged(sent, false))
 	}
 	p.logger.Info("completed task", "reward", resp.JSON200.Data.Reward)
+	p.bus.Publish(events.TaskCompleted{Meta: events.NewMeta(p.Name, p.clock), Reward: resp.JSON200.Data.Reward})
 	p.UpdateData(resp.JSON200.Data.Character)
 
 	return &resp.JSON200.Data.Reward, resp.StatusCode()
@@ -48,15 +56,48 @@ func (p *Player) ExchangeTaskCoins(tile models.MapTile) (*client.TaskRewardSchem
 	if code := p.move(tile.X, tile.Y); code != http.StatusOK {
 		return nil, code
 	}
+	p.logger.Debug("exchanging task coins")
+	sent := p.clock.Now()
 	resp, err := p.client.ActionTaskExchangeMyNameActionTaskExchangePostWithResponse(p.ctx, p.Name)
-
 	if err != nil {
-		panic(err)
+		return nil, p.reconcile("task exchange", sent, 0, err, inventoryChanged(sent))
 	}
+	metrics.ObserveAction(p.Name, "task_exchange", resp.StatusCode())
 	if resp.StatusCode() != 200 {
-		return nil, resp.StatusCode()
+		return nil, p.reconcile("task exchange", sent, resp.StatusCode(), nil, inventoryChanged(sent))
 	}
 
+	p.logger.Info("exchanged task coins", "reward", resp.JSON200.Data.Reward)
+	p.bus.Publish(events.TaskCoinsExchanged{Meta: events.NewMeta(p.Name, p.clock), Reward: resp.JSON200.Data.Reward})
 	p.UpdateData(resp.JSON200.Data.Character)
 	return &resp.JSON200.Data.Reward, resp.StatusCode()
 }
+
+// CancelTask drops the current task, the game charges a task coin from the inventory
+func (p *Player) CancelTask(tile models.MapTile) int {
+	if code := p.move(tile.X, tile.Y); code != http.StatusOK {
+		return code
+	}
+	p.logger.Debug("cancelling task")
+	task := p.Data().Task
+	sent := p.clock.Now()
+	resp, err := p.client.ActionTaskCancelMyNameActionTaskCancelPostWithResponse(p.ctx, p.Name)
+	if err != nil {
+		return p.reconcile("task cancel", sent, 0, err, taskChanged(sent, false))
+	}
+	metrics.ObserveAction(p.Name, "task_cancel", resp.StatusCode())
+	if resp.StatusCode() != 200 {
+		return p.reconcile("task cancel", sent, resp.StatusCode(), nil, taskChanged(sent, false))
+	}
+
+	p.logger.Info("cancelled task", "task", task)
+	cancelled := events.TaskCancelled{Meta: events.NewMeta(p.Name, p.clock)}
+	if task != nil {
+		cancelled.Code = task.Code
+		cancelled.Type = task.Type
+	}
+	p.bus.Publish(cancelled)
+	p.UpdateData(resp.JSON200.Data.Character)
+
+	return resp.StatusCode()
+}
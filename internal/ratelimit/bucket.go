@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket whose waiters are served round robin by key, so one busy character cannot starve the others
+type bucket struct {
+	name   string
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	// last is when tokens were counted, it is in the future while the bucket is paused
+	last   time.Time
+	queues map[string][]*waiter
+	// order holds the keys with waiters, next is whose turn it is
+	order []string
+	next  int
+	timer *time.Timer
+}
+
+type waiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newBucket(name string, b Budget) *bucket {
+	return &bucket{
+		name:   name,
+		rate:   b.Rate,
+		burst:  float64(b.Burst),
+		tokens: float64(b.Burst),
+		last:   time.Now(),
+		queues: map[string][]*waiter{},
+	}
+}
+
+// wait blocks until the key is granted a token or the context is done
+func (b *bucket) wait(ctx context.Context, key string) error {
+	if b.rate <= 0 {
+		return nil
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	b.mu.Lock()
+	if len(b.queues[key]) == 0 {
+		b.order = append(b.order, key)
+	}
+	b.queues[key] = append(b.queues[key], w)
+	b.grant()
+	b.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w.granted {
+		//granted while giving up, hand the token to the next waiter
+		b.tokens++
+		b.grant()
+	} else {
+		b.remove(key, w)
+	}
+	return ctx.Err()
+}
+
+// pause empties the bucket until the time, the api said the budget is spent
+func (b *bucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.tokens = 0
+	if until.After(b.last) {
+		b.last = until
+	}
+}
+
+// grant hands out the available tokens to the waiting keys in turn, the lock must be held
+func (b *bucket) grant() {
+	now := time.Now()
+	b.refill(now)
+	for len(b.order) > 0 {
+		if b.tokens < 1 {
+			b.schedule(b.last.Sub(now) + time.Duration((1-b.tokens)/b.rate*float64(time.Second)))
+			return
+		}
+
+		b.next %= len(b.order)
+		key := b.order[b.next]
+		queue := b.queues[key]
+		w := queue[0]
+		b.queues[key] = queue[1:]
+		b.tokens--
+		w.granted = true
+		close(w.ready)
+
+		if len(b.queues[key]) == 0 {
+			delete(b.queues, key)
+			b.order = slices.Delete(b.order, b.next, b.next+1)
+		} else {
+			b.next++
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// schedule wakes the waiters once the next token is due, one timer serves all of them
+func (b *bucket) schedule(d time.Duration) {
+	if b.timer != nil {
+		return
+	}
+	b.timer = time.AfterFunc(d, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.timer = nil
+		b.grant()
+	})
+}
+
+func (b *bucket) remove(key string, w *waiter) {
+	queue := b.queues[key]
+	i := slices.Index(queue, w)
+	if i < 0 {
+		return
+	}
+	b.queues[key] = slices.Delete(queue, i, i+1)
+	if len(b.queues[key]) > 0 {
+		return
+	}
+
+	delete(b.queues, key)
+	k := slices.Index(b.order, key)
+	b.order = slices.Delete(b.order, k, k+1)
+	if k < b.next {
+		b.next--
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/metrics"
+	"github.com/sagikazarmark/slog-shim"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// the api allows 7200 requests an hour for actions and for data, with bursts of 7 actions and 16 data requests
+	defaultRate        = 2
+	defaultActionBurst = 7
+	defaultDataBurst   = 16
+	// defaultRetryAfter is the pause after a 429 without a usable Retry-After header
+	defaultRetryAfter = time.Second
+)
+
+// Budget is a token bucket refilled with Rate tokens per second and holding up to Burst, a negative rate turns it off
+type Budget struct {
+	Rate  float64 `yaml:"rate" mapstructure:"rate"`
+	Burst int     `yaml:"burst" mapstructure:"burst"`
+}
+
+// Config has a budget for character actions and one for everything else, the api limits them separately
+type Config struct {
+	Action Budget `yaml:"action" mapstructure:"action"`
+	Data   Budget `yaml:"data" mapstructure:"data"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Action: Budget{Rate: defaultRate, Burst: defaultActionBurst},
+		Data:   Budget{Rate: defaultRate, Burst: defaultDataBurst},
+	}
+}
+
+// WithDefaults fills unset fields from DefaultConfig
+func (c Config) WithDefaults() Config {
+	d := DefaultConfig()
+	c.Action = c.Action.withDefaults(d.Action)
+	c.Data = c.Data.withDefaults(d.Data)
+	return c
+}
+
+func (b Budget) withDefaults(d Budget) Budget {
+	if b.Rate == 0 {
+		b.Rate = d.Rate
+	}
+	if b.Burst <= 0 {
+		b.Burst = d.Burst
+	}
+	return b
+}
+
+// Transport holds requests until their budget has a token, characters take turns when requests queue up.
+// A 429 from the api pauses the budget for as long as Retry-After asks.
+type Transport struct {
+	Base   http.RoundTripper
+	action *bucket
+	data   *bucket
+	logger *slog.Logger
+}
+
+func NewTransport(base http.RoundTripper, cfg Config) *Transport {
+	cfg = cfg.WithDefaults()
+	return &Transport{
+		Base:   base,
+		action: newBucket("action", cfg.Action),
+		data:   newBucket("data", cfg.Data),
+		logger: slog.Default().With("source", "ratelimit"),
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.budget(req)
+	start := time.Now()
+	if err := b.wait(req.Context(), api.Character(req.URL.Path)); err != nil {
+		return nil, err
+	}
+	metrics.ObserveRateLimitWait(b.name, time.Since(start))
+
+	resp, err := t.Base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		d := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		t.logger.Warn("rate limited by the api", "budget", b.name, "retry_after", d)
+		b.pause(time.Now().Add(d))
+	}
+	return resp, err
+}
+
+func (t *Transport) budget(req *http.Request) *bucket {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if req.Method == http.MethodPost && len(parts) >= 3 && parts[0] == "my" && parts[2] == "action" {
+		return t.action
+	}
+	return t.data
+}
+
+// retryAfter reads the header as seconds or as a date
+func retryAfter(header string, now time.Time) time.Duration {
+	if s, err := strconv.Atoi(header); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return defaultRetryAfter
+}
@@ -0,0 +1,131 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sagikazarmark/slog-shim"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const redacted = "[redacted]"
+
+// Exchange is one request and what the api answered, a recording is a file with one exchange per line
+type Exchange struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	// URL is the path and query, the host is dropped so recordings do not depend on the api address
+	URL string `json:"url"`
+	// Header is the request header with the token redacted
+	Header         http.Header `json:"header,omitempty"`
+	Body           string      `json:"body,omitempty"`
+	Status         int         `json:"status,omitempty"`
+	ResponseHeader http.Header `json:"response_header,omitempty"`
+	Response       string      `json:"response,omitempty"`
+	// Error is set when the request failed without a response, e.g. a timeout
+	Error string `json:"error,omitempty"`
+}
+
+// Recorder writes every exchange of the transports it wraps to a file
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// Create starts a new recording, replacing the file if it exists
+func Create(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create recording %s: %w", path, err)
+	}
+	return &Recorder{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Transport records the requests sent through base
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &recordingTransport{recorder: r, base: base}
+}
+
+func (r *Recorder) write(e Exchange) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(e)
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	base     http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := Exchange{
+		Time:   time.Now(),
+		Method: req.Method,
+		URL:    req.URL.RequestURI(),
+		Header: redact(req.Header),
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		b, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		e.Body = string(b)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		e.Error = err.Error()
+		t.write(e)
+		return resp, err
+	}
+
+	//the body is read here and handed back from memory so the caller sees it unchanged
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		e.Error = err.Error()
+		t.write(e)
+		return resp, fmt.Errorf("read response body: %w", err)
+	}
+	e.Status = resp.StatusCode
+	e.ResponseHeader = resp.Header.Clone()
+	e.Response = string(b)
+	t.write(e)
+
+	return resp, nil
+}
+
+// write never fails the request, a recording with a gap beats a broken session
+func (t *recordingTransport) write(e Exchange) {
+	if err := t.recorder.write(e); err != nil {
+		slog.Warn("could not record request", "method", e.Method, "url", e.URL, "error", err)
+	}
+}
+
+func redact(h http.Header) http.Header {
+	h = h.Clone()
+	if h.Get("Authorization") != "" {
+		h.Set("Authorization", redacted)
+	}
+	return h
+}
@@ -0,0 +1,180 @@
+package replay
+
+import (
+	"artifactsmmo/internal/api"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Miss is a request the recording has no answer for
+type Miss struct {
+	Method    string
+	URL       string
+	Character string
+	// Remaining is how many recorded actions of the character were never sent, zero when the recording just ran out
+	Remaining int
+}
+
+func (m Miss) Error() string {
+	return fmt.Sprintf("%s %s not in recording, %s had %d more actions", m.Method, m.URL, m.Character, m.Remaining)
+}
+
+// Diverged tells a replay that took a different path from one that reached the end of the recording
+func (m Miss) Diverged() bool {
+	return m.Remaining > 0
+}
+
+// Replayer answers requests from a recording instead of the api.
+// Each request is matched on method, url and body and gets the recorded responses in order,
+// so players sharing the replayer can interleave differently than in the recorded session.
+type Replayer struct {
+	mu         sync.Mutex
+	start      time.Time
+	characters []string
+	exchanges  map[string][]Exchange
+	total      int
+	served     int
+	miss       *Miss
+}
+
+// Load reads a recording written by a Recorder
+func Load(path string) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording %s: %w", path, err)
+	}
+	defer f.Close()
+
+	r := &Replayer{exchanges: map[string][]Exchange{}}
+	seen := map[string]bool{}
+	dec := json.NewDecoder(f)
+	for {
+		var e Exchange
+		if err := dec.Decode(&e); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read recording %s: %w", path, err)
+		}
+		if r.total == 0 {
+			r.start = e.Time
+		}
+		r.total++
+
+		key := key(e.Method, e.URL, e.Body)
+		r.exchanges[key] = append(r.exchanges[key], e)
+		if name := character(e.URL); name != "" && !seen[name] {
+			seen[name] = true
+			r.characters = append(r.characters, name)
+		}
+	}
+	if r.total == 0 {
+		return nil, fmt.Errorf("recording %s is empty", path)
+	}
+	return r, nil
+}
+
+// Start is when the recording began, replays should run on a clock starting there so recorded cooldowns line up
+func (r *Replayer) Start() time.Time {
+	return r.start
+}
+
+// Characters are the characters in the recording in order of their first request
+func (r *Replayer) Characters() []string {
+	return r.characters
+}
+
+// Served is how many of the recorded exchanges were replayed so far
+func (r *Replayer) Served() (served, total int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.served, r.total
+}
+
+// Miss is the first request the recording could not answer
+func (r *Replayer) Miss() (Miss, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.miss == nil {
+		return Miss{}, false
+	}
+	return *r.miss, true
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+		req.Body.Close()
+	}
+	url := req.URL.RequestURI()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := key(req.Method, url, string(body))
+	queue := r.exchanges[k]
+	if len(queue) == 0 {
+		return r.missed(req, url), nil
+	}
+	e := queue[0]
+	r.exchanges[k] = queue[1:]
+	r.served++
+
+	if e.Error != "" {
+		return nil, errors.New(e.Error)
+	}
+	return response(req, e.Status, e.ResponseHeader, e.Response), nil
+}
+
+// missed answers with an error the players pass on to the engine, failing the transport would look like a timeout
+func (r *Replayer) missed(req *http.Request, url string) *http.Response {
+	m := Miss{Method: req.Method, URL: url, Character: character(url)}
+	for _, queue := range r.exchanges {
+		for _, e := range queue {
+			if e.Method == http.MethodPost && m.Character != "" && character(e.URL) == m.Character {
+				m.Remaining++
+			}
+		}
+	}
+	if r.miss == nil {
+		r.miss = &m
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"error": map[string]any{"code": http.StatusNotImplemented, "message": m.Error()},
+	})
+	return response(req, http.StatusNotImplemented, http.Header{"Content-Type": {"application/json"}}, string(body))
+}
+
+func response(req *http.Request, status int, header http.Header, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func key(method, url, body string) string {
+	return method + " " + url + "\n" + body
+}
+
+func character(url string) string {
+	path, _, _ := strings.Cut(url, "?")
+	return api.Character(path)
+}
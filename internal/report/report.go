@@ -0,0 +1,163 @@
+package report
+
+import (
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/history"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const stepPlannedAction = "step_planned"
+
+// Pricer values items at grand exchange prices, e.g. world.Prices or the collector
+type Pricer interface {
+	ItemPrice(code string) int
+}
+
+// Row is the actual and predicted return of one activity for one character
+type Row struct {
+	Character   string
+	Skill       string
+	Activity    string
+	Actions     int
+	Xp          int
+	Gold        int
+	Value       int
+	Duration    time.Duration
+	predictions []history.Prediction
+}
+
+func (r Row) XpPerHour() float64 {
+	return perHour(r.Xp, r.Duration)
+}
+
+// GoldPerHour counts gold and what the drops sell for, like the prediction does
+func (r Row) GoldPerHour() float64 {
+	return perHour(r.Gold+r.Value, r.Duration)
+}
+
+// Predicted averages the predictions made when the activity was planned, ok is false when none were made
+func (r Row) Predicted() (history.Prediction, bool) {
+	var p history.Prediction
+	n := 0
+	for _, pr := range r.predictions {
+		if pr.XpPerHour == 0 && pr.GoldPerHour == 0 {
+			continue
+		}
+		p.XpPerHour += pr.XpPerHour
+		p.GoldPerHour += pr.GoldPerHour
+		n++
+	}
+	if n == 0 {
+		return p, false
+	}
+	return history.Prediction{XpPerHour: p.XpPerHour / float64(n), GoldPerHour: p.GoldPerHour / float64(n)}, true
+}
+
+// Build computes a row per character and gathering or fighting activity from the history between from and to.
+// Time between two planned activities is attributed to the first, so travel and banking count against the activity.
+// Actions recorded without a planned step only count their own cooldown. Drops are valued with the prices.
+func Build(store *history.Store, characters []string, from, to time.Time, prices Pricer) ([]Row, error) {
+	rows := make([]Row, 0)
+	for _, c := range characters {
+		records, err := store.Query(c, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("query history for %s: %w", c, err)
+		}
+		rows = append(rows, buildCharacter(c, records, prices)...)
+	}
+
+	slices.SortFunc(rows, func(a, b Row) int {
+		if a.Character != b.Character {
+			return strings.Compare(a.Character, b.Character)
+		}
+		if a.Skill != b.Skill {
+			return strings.Compare(a.Skill, b.Skill)
+		}
+		return cmp.Compare(b.XpPerHour(), a.XpPerHour())
+	})
+
+	return rows, nil
+}
+
+func buildCharacter(character string, records []history.Record, prices Pricer) []Row {
+	rows := map[string]*Row{}
+	row := func(activity string) *Row {
+		if r, ok := rows[activity]; ok {
+			return r
+		}
+		r := &Row{Character: character, Activity: activity}
+		rows[activity] = r
+		return r
+	}
+
+	var current *history.Record
+	var last time.Time
+	closeSegment := func(end time.Time) {
+		if current != nil {
+			row(current.Request).Duration += end.Sub(current.Time)
+		}
+	}
+
+	for i, r := range records {
+		switch {
+		case !commands.IsActivity(r.Request):
+			//deposits, tasks and level ups, their time goes to the activity they happen during
+		case r.Action == stepPlannedAction:
+			closeSegment(r.Time)
+			current = &records[i]
+			if r.Predicted != nil {
+				row(r.Request).predictions = append(row(r.Request).predictions, *r.Predicted)
+			}
+		default:
+			target := row(r.Request)
+			if current == nil || current.Request != r.Request {
+				target.Duration += time.Duration(r.Cooldown) * time.Second
+			}
+			target.Actions++
+			target.Xp += r.Xp
+			target.Gold += r.Gold
+			for _, d := range r.Drops {
+				target.Value += prices.ItemPrice(d.Code) * d.Quantity
+			}
+			if r.Skill != "" {
+				target.Skill = r.Skill
+			}
+		}
+		last = r.Time.Add(time.Duration(r.Cooldown) * time.Second)
+	}
+	closeSegment(last)
+
+	res := make([]Row, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, *r)
+	}
+	return res
+}
+
+func Write(w io.Writer, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHARACTER\tSKILL\tACTIVITY\tACTIONS\tHOURS\tXP/H\tGOLD/H\tPREDICTED XP/H\tPREDICTED GOLD/H")
+	for _, r := range rows {
+		predictedXp, predictedGold := "-", "-"
+		if p, ok := r.Predicted(); ok {
+			predictedXp = fmt.Sprintf("%.0f", p.XpPerHour)
+			predictedGold = fmt.Sprintf("%.0f", p.GoldPerHour)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\t%.0f\t%.0f\t%s\t%s\n",
+			r.Character, r.Skill, r.Activity, r.Actions, r.Duration.Hours(), r.XpPerHour(), r.GoldPerHour(), predictedXp, predictedGold)
+	}
+	return tw.Flush()
+}
+
+func perHour(v int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(v) / d.Hours()
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"artifactsmmo/internal/history"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const exchangeAction = "task_coins_exchanged"
+
+// Reward totals one item received from task coin exchanges
+type Reward struct {
+	Character string
+	Code      string
+	Times     int
+	Quantity  int
+}
+
+func Rewards(store *history.Store, characters []string, from, to time.Time) ([]Reward, error) {
+	rewards := make([]Reward, 0)
+	for _, c := range characters {
+		records, err := store.Query(c, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("query history for %s: %w", c, err)
+		}
+
+		byCode := map[string]*Reward{}
+		for _, r := range records {
+			if r.Action != exchangeAction {
+				continue
+			}
+			for _, d := range r.Drops {
+				rw, ok := byCode[d.Code]
+				if !ok {
+					rw = &Reward{Character: c, Code: d.Code}
+					byCode[d.Code] = rw
+				}
+				rw.Times++
+				rw.Quantity += d.Quantity
+			}
+		}
+		for _, rw := range byCode {
+			rewards = append(rewards, *rw)
+		}
+	}
+
+	slices.SortFunc(rewards, func(a, b Reward) int {
+		if a.Character != b.Character {
+			return strings.Compare(a.Character, b.Character)
+		}
+		return cmp.Compare(b.Quantity, a.Quantity)
+	})
+	return rewards, nil
+}
+
+func WriteRewards(w io.Writer, rewards []Reward) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHARACTER\tEXCHANGE REWARD\tTIMES\tQUANTITY")
+	for _, r := range rewards {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", r.Character, r.Code, r.Times, r.Quantity)
+	}
+	return tw.Flush()
+}
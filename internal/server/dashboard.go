@@ -0,0 +1,116 @@
+package server
+
+import (
+	"artifactsmmo/internal/engine"
+	"artifactsmmo/internal/events"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+const statusInterval = time.Second
+
+//go:embed static
+var static embed.FS
+
+// feedEvent is the dashboard representation of a bus event
+type feedEvent struct {
+	Time    time.Time `json:"time"`
+	Event   string    `json:"event"`
+	Player  string    `json:"player"`
+	Message string    `json:"message"`
+}
+
+type dashboardStatus struct {
+	Players []engine.PlayerStatus `json:"players"`
+	Banks   []engine.BankStatus   `json:"banks"`
+}
+
+func staticHandler() http.Handler {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		//embedded at build time, this can only fail if the directive is wrong
+		panic(err)
+	}
+	return http.FileServerFS(sub)
+}
+
+func (s *Server) getMap(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.engine.MapTiles())
+}
+
+// streamEvents pushes the team status every statusInterval and feed events as they happen using server sent events
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "streaming not supported"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	feed, unsubscribe := s.engine.Bus().Subscribe()
+	defer unsubscribe()
+
+	ticker := time.NewTicker(statusInterval)
+	defer ticker.Stop()
+
+	if err := s.writeEvent(w, "status", s.dashboardStatus()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-feed:
+			//character updates are covered by the status event
+			if _, ok := ev.(events.CharacterUpdated); ok {
+				continue
+			}
+			info := ev.Info()
+			if err := s.writeEvent(w, "feed", feedEvent{
+				Time:    info.Time,
+				Event:   ev.Name(),
+				Player:  info.Character,
+				Message: ev.String(),
+			}); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := s.writeEvent(w, "status", s.dashboardStatus()); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func (s *Server) dashboardStatus() dashboardStatus {
+	status := dashboardStatus{
+		Players: make([]engine.PlayerStatus, 0),
+		Banks:   s.engine.Banks(),
+	}
+	for _, name := range s.engine.Players() {
+		if p, err := s.engine.PlayerStatus(name); err == nil {
+			status.Players = append(status.Players, p)
+		}
+	}
+	return status
+}
+
+func (s *Server) writeEvent(w http.ResponseWriter, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.logger.Warn("encoding event", "event", event, "error", err)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
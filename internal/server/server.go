@@ -0,0 +1,144 @@
+package server
+
+import (
+	"artifactsmmo/internal/engine"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sagikazarmark/slog-shim"
+	"net/http"
+	"time"
+)
+
+// Server exposes engine status and controls over http, it is meant to be bound to a local address
+type Server struct {
+	engine *engine.GameEngine
+	srv    *http.Server
+	Out    chan error
+	logger *slog.Logger
+}
+
+func NewServer(addr string, e *engine.GameEngine) *Server {
+	s := &Server{
+		engine: e,
+		Out:    make(chan error),
+		logger: slog.Default().With("source", "server"),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /players", s.listPlayers)
+	mux.HandleFunc("GET /players/{name}", s.getPlayer)
+	mux.HandleFunc("POST /players/{name}/pause", s.pausePlayer)
+	mux.HandleFunc("POST /players/{name}/resume", s.resumePlayer)
+	mux.HandleFunc("POST /players/{name}/goals", s.enqueueGoal)
+	mux.HandleFunc("POST /players/{name}/deposit", s.forceDeposit)
+	mux.HandleFunc("GET /bank", s.getBank)
+	mux.HandleFunc("GET /map", s.getMap)
+	mux.HandleFunc("GET /events", s.streamEvents)
+	mux.Handle("GET /metrics", promhttp.Handler())
+	mux.Handle("GET /", staticHandler())
+
+	s.srv = &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	return s
+}
+
+// Start serves until the context is cancelled, errors are sent on Out
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		s.logger.Info("starting status server", "addr", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.Out <- fmt.Errorf("status server: %w", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.srv.Shutdown(shutdownCtx); err != nil {
+			s.logger.Warn("status server shutdown", "error", err)
+		}
+	}()
+}
+
+func (s *Server) listPlayers(w http.ResponseWriter, _ *http.Request) {
+	res := make([]engine.PlayerStatus, 0)
+	for _, name := range s.engine.Players() {
+		status, err := s.engine.PlayerStatus(name)
+		if err != nil {
+			s.writeError(w, err)
+			return
+		}
+		res = append(res, status)
+	}
+	s.writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) getPlayer(w http.ResponseWriter, r *http.Request) {
+	status, err := s.engine.PlayerStatus(r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) pausePlayer(w http.ResponseWriter, r *http.Request) {
+	s.control(w, s.engine.Pause(r.PathValue("name")))
+}
+
+func (s *Server) resumePlayer(w http.ResponseWriter, r *http.Request) {
+	s.control(w, s.engine.Resume(r.PathValue("name")))
+}
+
+func (s *Server) forceDeposit(w http.ResponseWriter, r *http.Request) {
+	s.control(w, s.engine.ForceDeposit(r.PathValue("name")))
+}
+
+func (s *Server) enqueueGoal(w http.ResponseWriter, r *http.Request) {
+	var g engine.Goal
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid goal: %s", err)})
+		return
+	}
+	s.control(w, s.engine.Enqueue(r.PathValue("name"), g))
+}
+
+func (s *Server) getBank(w http.ResponseWriter, _ *http.Request) {
+	s.writeJSON(w, http.StatusOK, s.engine.Banks())
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) control(w http.ResponseWriter, err error) {
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	var notFound engine.PlayerNotFound
+	if errors.As(err, &notFound) {
+		code = http.StatusNotFound
+	}
+	s.writeJSON(w, code, errorResponse{Error: err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Warn("writing response", "error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package simulate
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/engine"
+	"artifactsmmo/internal/replay"
+	"artifactsmmo/internal/strategy"
+	"artifactsmmo/internal/world"
+	"context"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+)
+
+// replayURL is never dialed, the replayer answers every request
+const replayURL = "http://replay.invalid"
+
+type ReplayOptions struct {
+	Recording *replay.Replayer
+	// Objectives and TaskPolicy should match the recorded session, otherwise the engine plans differently
+	Objectives map[string]strategy.Objective
+	TaskPolicy strategy.TaskPolicy
+}
+
+type ReplayResult struct {
+	Served int
+	Total  int
+	// Miss is the request that ended the replay
+	Miss replay.Miss
+}
+
+// Replay runs the engine against a recording on a fake clock starting where the recording did.
+// Recordings hold the responses after retries, so the client replays them without retrying.
+// It ends at the first request the recording cannot answer, a miss with actions left means the engine decided differently.
+func Replay(ctx context.Context, opts ReplayOptions) (ReplayResult, error) {
+	rec := opts.Recording
+	clk := clock.NewFake(rec.Start())
+	c, err := client.NewClientWithResponses(replayURL, client.WithHTTPClient(&http.Client{Transport: rec}))
+	if err != nil {
+		return ReplayResult{}, fmt.Errorf("create client: %w", err)
+	}
+
+	replayCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	names := rec.Characters()
+	game, err := engine.NewGameEngine(replayCtx, engine.GameConfig{
+		API:         c,
+		PlayerNames: names,
+		Objectives:  opts.Objectives,
+		TaskPolicy:  opts.TaskPolicy,
+		Clock:       clk,
+		Refresh:     world.NoRefresh(),
+	})
+	if err == nil {
+		err = drive(replayCtx, game, clk, names, func() bool {
+			_, missed := rec.Miss()
+			return missed
+		})
+	}
+
+	res := ReplayResult{}
+	res.Served, res.Total = rec.Served()
+	miss, missed := rec.Miss()
+	if !missed {
+		if err == nil {
+			err = fmt.Errorf("replay stopped without a miss")
+		}
+		return res, fmt.Errorf("replay: %w", err)
+	}
+	//the engine stops on the error the miss answers with, that is the expected end
+	res.Miss = miss
+	return res, nil
+}
@@ -0,0 +1,211 @@
+package simulate
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/engine"
+	"artifactsmmo/internal/fake"
+	"artifactsmmo/internal/strategy"
+	"artifactsmmo/internal/world"
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	pollInterval = 100 * time.Microsecond
+	// stallTimeout stops a simulation where a character stops acting, e.g. a strategy stuck without a step
+	stallTimeout = 10 * time.Second
+	// settlePolls is how many polls every player has to stay idle before the simulation ends
+	settlePolls = 20
+)
+
+type Options struct {
+	Snapshot   world.Snapshot
+	Characters []client.CharacterSchema
+	// Duration is the game time to play
+	Duration   time.Duration
+	Objectives map[string]strategy.Objective
+	TaskPolicy strategy.TaskPolicy
+	Seed       int64
+}
+
+// Result is what one character gained in the simulation
+type Result struct {
+	Character string
+	// Elapsed is the time the character spent on cooldowns, slightly over the duration as characters finish their step
+	Elapsed    time.Duration
+	StartLevel int
+	EndLevel   int
+	Gains      fake.Gains
+}
+
+// Run plays the engine against a fake server built from the snapshot.
+// Both share a fake clock that jumps to the next cooldown once every character waits on one, so a day runs in seconds.
+func Run(ctx context.Context, opts Options) ([]Result, error) {
+	clk := clock.NewFake(time.Now())
+	cfg := fake.FromSnapshot(opts.Snapshot, opts.Characters...)
+	cfg.GameCooldowns = true
+	cfg.Clock = clk
+	cfg.Seed = opts.Seed
+	srv := fake.NewServer(cfg)
+	defer srv.Close()
+
+	c, err := client.NewClientWithResponses(srv.URL)
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	simCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	names := make([]string, 0, len(opts.Characters))
+	for _, ch := range opts.Characters {
+		names = append(names, ch.Name)
+	}
+	game, err := engine.NewGameEngine(simCtx, engine.GameConfig{
+		API:         c,
+		PlayerNames: names,
+		Objectives:  opts.Objectives,
+		TaskPolicy:  opts.TaskPolicy,
+		Clock:       clk,
+		Refresh:     world.NoRefresh(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start engine: %w", err)
+	}
+
+	end := clk.Now().Add(opts.Duration)
+	if err = drive(simCtx, game, clk, names, func() bool { return !clk.Now().Before(end) }); err != nil {
+		return nil, fmt.Errorf("simulation: %w", err)
+	}
+	if err = stop(simCtx, game, clk, names); err != nil {
+		return nil, err
+	}
+	cancel()
+
+	results := make([]Result, 0, len(opts.Characters))
+	for _, start := range opts.Characters {
+		end, _ := srv.Character(start.Name)
+		results = append(results, Result{
+			Character:  start.Name,
+			Elapsed:    srv.Elapsed(start.Name),
+			StartLevel: start.Level,
+			EndLevel:   end.Level,
+			Gains:      srv.Gains(start.Name),
+		})
+	}
+	return results, nil
+}
+
+// drive advances the clock whenever every player that is not paused waits on a cooldown, until done
+func drive(ctx context.Context, game *engine.GameEngine, clk *clock.Fake, names []string, done func() bool) error {
+	start := clk.Now()
+	lastProgress := time.Now()
+
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-game.Out:
+			return fmt.Errorf("engine stopped: %w", err)
+		default:
+		}
+
+		if clk.Waiters() >= running(game, names) && clk.AdvanceToNext() {
+			lastProgress = time.Now()
+			continue
+		}
+		if time.Since(lastProgress) > stallTimeout {
+			return fmt.Errorf("stalled after %s of game time", clk.Now().Sub(start))
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil
+}
+
+// running counts the players that are not paused, the engine pauses players whose steps keep failing
+func running(game *engine.GameEngine, names []string) int {
+	n := 0
+	for _, name := range names {
+		if status, err := game.PlayerStatus(name); err == nil && !status.Paused {
+			n++
+		}
+	}
+	return n
+}
+
+// stop pauses every player and lets them finish their step, cancelling a request in flight loses its response
+func stop(ctx context.Context, game *engine.GameEngine, clk *clock.Fake, names []string) error {
+	for _, n := range names {
+		if err := game.Pause(n); err != nil {
+			return err
+		}
+	}
+
+	started := time.Now()
+	idle := 0
+	for idle < settlePolls {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(started) > stallTimeout {
+			return fmt.Errorf("players did not stop")
+		}
+
+		busy := false
+		for _, n := range names {
+			status, err := game.PlayerStatus(n)
+			if err != nil {
+				return err
+			}
+			busy = busy || status.CurrentStep != ""
+		}
+		if busy {
+			idle = 0
+			clk.AdvanceToNext()
+		} else {
+			idle++
+		}
+		time.Sleep(pollInterval)
+	}
+	return nil
+}
+
+// Write prints a summary line per character followed by the items it gained
+func Write(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHARACTER\tGAME TIME\tLEVEL\tGOLD\tXP\tITEMS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d -> %d\t%d\t%s\t%s\n",
+			r.Character, r.Elapsed.Round(time.Minute), r.StartLevel, r.EndLevel, r.Gains.Gold, formatCounts(r.Gains.Xp), formatCounts(r.Gains.Items))
+	}
+	return tw.Flush()
+}
+
+// formatCounts lists the largest counts first, e.g. "ash_wood 120, copper_ore 8"
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s %d", k, counts[k]))
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,308 @@
+package strategy
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/commands"
+	"artifactsmmo/internal/history"
+	"artifactsmmo/internal/models"
+	"artifactsmmo/internal/player"
+	"artifactsmmo/internal/world"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// rough game mechanics used until there is observed history for an activity
+const (
+	gatherSeconds       = 25
+	fightSecondsPerTurn = 2
+	depositSeconds      = 10
+	xpLevelRange        = 10
+	baseXpPerAction     = 5
+	xpPerLevel          = 2
+	maxActionsPerStep   = 20
+	observedRefresh     = 10 * time.Minute
+	observedWindow      = 7 * 24 * time.Hour
+	minObservedActions  = 5
+	defaultXpWeight     = 1
+	defaultGoldWeight   = 0.5
+)
+
+var gatheringSkills = []string{models.WoodcuttingSkill, models.FishingSkill, models.MiningSkill}
+
+type CandidateType string
+
+const (
+	GatherCandidate CandidateType = "gather"
+	FightCandidate  CandidateType = "fight"
+)
+
+// Objective weights what a character should optimise for, Skills limits xp to the listed skills when set
+type Objective struct {
+	XpWeight   float64  `yaml:"xp_weight" mapstructure:"xp_weight"`
+	GoldWeight float64  `yaml:"gold_weight" mapstructure:"gold_weight"`
+	Skills     []string `yaml:"skills" mapstructure:"skills"`
+}
+
+func DefaultObjective() Objective {
+	return Objective{XpWeight: defaultXpWeight, GoldWeight: defaultGoldWeight}
+}
+
+// Candidate is an activity the character could do next with its estimated cost and return
+type Candidate struct {
+	Type     CandidateType
+	Code     string
+	Skill    string
+	Tile     models.MapTile
+	Quantity int
+	// Drops are the items the activity can produce with the quantity expected per action
+	Drops map[string]float64
+	// per action estimates
+	Xp            float64
+	Gold          float64
+	DropValue     float64
+	ActionSeconds float64
+	BankSeconds   float64
+	// one time travel to the tile
+	TravelSeconds float64
+	// Event is the game event on the tile, the activity is only available until Expiration
+	Event      string
+	Expiration time.Time
+}
+
+func (c Candidate) Activity() string {
+	if c.Type == FightCandidate {
+		return commands.FightActivity(c.Code)
+	}
+	return commands.GatherActivity(c.Code)
+}
+
+func (c Candidate) Seconds() float64 {
+	return c.TravelSeconds + float64(c.Quantity)*(c.ActionSeconds+c.BankSeconds)
+}
+
+func (c Candidate) XpPerSecond() float64 {
+	return float64(c.Quantity) * c.Xp / c.Seconds()
+}
+
+func (c Candidate) ValuePerSecond() float64 {
+	return float64(c.Quantity) * (c.Gold + c.DropValue) / c.Seconds()
+}
+
+func (c Candidate) String() string {
+	return fmt.Sprintf("%s x%d (%.2f xp/s %.2f gold/s)", c.Activity(), c.Quantity, c.XpPerSecond(), c.ValuePerSecond())
+}
+
+// Scorer ranks gathering and fighting candidates by estimated xp and value per second
+type Scorer struct {
+	world    *world.Collector
+	history  *history.Store
+	clock    clock.Clock
+	mu       sync.Mutex
+	observed map[string]observation
+}
+
+type observation struct {
+	loaded   time.Time
+	averages map[string]history.Average
+}
+
+// NewScorer creates a scorer, store is optional and improves estimates with observed results
+func NewScorer(w *world.Collector, store *history.Store, clk clock.Clock) *Scorer {
+	return &Scorer{
+		world:    w,
+		history:  store,
+		clock:    clk,
+		observed: map[string]observation{},
+	}
+}
+
+// Best returns the highest scoring candidate for the objective
+func (s *Scorer) Best(p *player.Player, o Objective) (Candidate, error) {
+	candidates := s.Candidates(p)
+	if len(candidates) == 0 {
+		return Candidate{}, fmt.Errorf("no candidates for %s", p.Name)
+	}
+
+	slices.SortStableFunc(candidates, func(a, b Candidate) int {
+		sa, sb := score(a, o), score(b, o)
+		switch {
+		case sa > sb:
+			return -1
+		case sa < sb:
+			return 1
+		}
+		return 0
+	})
+	return candidates[0], nil
+}
+
+// BestEvent returns the highest scoring candidate on an active game event tile, false when the player qualifies for none
+func (s *Scorer) BestEvent(p *player.Player, o Objective) (Candidate, bool) {
+	var best Candidate
+	found := false
+	for _, c := range s.Candidates(p) {
+		if c.Event == "" {
+			continue
+		}
+		if !found || score(c, o) > score(best, o) {
+			best = c
+			found = true
+		}
+	}
+	return best, found
+}
+
+// Candidates lists every resource and winnable monster the player can reach
+func (s *Scorer) Candidates(p *player.Player) []Candidate {
+	data := p.Data()
+	observed := s.observedFor(p.Name)
+	capacity := data.MaxInventory
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	res := make([]Candidate, 0)
+	for _, skill := range gatheringSkills {
+		level := data.Skills[skill]
+		for _, r := range s.world.GetResourcesBySkill(skill, level) {
+			tile, ok := s.world.FindClosestTile(r.Code, data.Pos.X, data.Pos.Y)
+			if !ok {
+				continue
+			}
+			items := 0.0
+			value := 0.0
+			drops := make(map[string]float64, len(r.Drops))
+			for _, d := range r.Drops {
+				if d.Rate <= 0 {
+					continue
+				}
+				qty := float64(d.MinQuantity+d.MaxQuantity) / 2
+				drops[d.Code] += qty / float64(d.Rate)
+				items += qty / float64(d.Rate)
+				value += qty * float64(s.world.ItemPrice(d.Code)) / float64(d.Rate)
+			}
+			c := Candidate{
+				Type:          GatherCandidate,
+				Code:          r.Code,
+				Skill:         skill,
+				Tile:          tile,
+				Drops:         drops,
+				Xp:            estimateXp(level, r.Level),
+				DropValue:     value,
+				ActionSeconds: gatherSeconds,
+			}
+			if s.finish(&c, data, items, capacity, observed) {
+				res = append(res, c)
+			}
+		}
+	}
+
+	for _, m := range s.world.FilterMonsters(p) {
+		win, turns := p.EstimateFight(m)
+		if !win {
+			continue
+		}
+		tile, ok := s.world.FindClosestTile(m.Code, data.Pos.X, data.Pos.Y)
+		if !ok {
+			continue
+		}
+		items := 0.0
+		value := 0.0
+		drops := make(map[string]float64, len(m.Drops))
+		for _, d := range m.Drops {
+			if d.Rate <= 0 {
+				continue
+			}
+			qty := float64(d.MinQuantity+d.MaxQuantity) / 2
+			drops[d.Code] += qty / float64(d.Rate)
+			items += qty / float64(d.Rate)
+			value += qty * float64(s.world.ItemPrice(d.Code)) / float64(d.Rate)
+		}
+		c := Candidate{
+			Type:          FightCandidate,
+			Code:          m.Code,
+			Skill:         models.CombatSkill,
+			Tile:          tile,
+			Drops:         drops,
+			Xp:            estimateXp(data.Level, m.Level),
+			Gold:          float64(m.MinGold+m.MaxGold) / 2,
+			DropValue:     value,
+			ActionSeconds: float64(turns * fightSecondsPerTurn),
+		}
+		if s.finish(&c, data, items, capacity, observed) {
+			res = append(res, c)
+		}
+	}
+
+	return res
+}
+
+// finish applies observed results, travel and bank trips and picks a quantity that fits the inventory.
+// It returns false for event activities that would expire before the first action.
+func (s *Scorer) finish(c *Candidate, data player.PlayerData, itemsPerAction float64, capacity int, observed map[string]history.Average) bool {
+	if avg, ok := observed[c.Activity()]; ok && avg.Actions >= minObservedActions {
+		c.Xp = avg.XpPerAction
+		c.Gold = avg.GoldPerAction
+		if avg.CooldownSeconds > 0 {
+			c.ActionSeconds = avg.CooldownSeconds
+		}
+	}
+
+	c.TravelSeconds = float64(world.TravelSeconds(data.Pos.X, data.Pos.Y, c.Tile.X, c.Tile.Y))
+
+	c.Quantity = maxActionsPerStep
+	if itemsPerAction > 0 {
+		c.Quantity = min(maxActionsPerStep, max(1, int(float64(capacity)/itemsPerAction)))
+		if bank, ok := s.world.ClosestByContentType(world.BankMapContentType, c.Tile.X, c.Tile.Y); ok {
+			roundTrip := float64(world.RouteSeconds(c.Tile.X, c.Tile.Y, []models.MapTile{bank, c.Tile}) + depositSeconds)
+			c.BankSeconds = roundTrip * itemsPerAction / float64(capacity)
+		}
+	}
+
+	if e, ok := s.world.EventAt(c.Tile.ID()); ok {
+		c.Event = e.Name
+		c.Expiration = e.Expiration
+		left := e.Expiration.Sub(s.clock.Now()).Seconds() - c.TravelSeconds
+		if perAction := c.ActionSeconds + c.BankSeconds; perAction > 0 {
+			c.Quantity = min(c.Quantity, int(left/perAction))
+		}
+	}
+	return c.Quantity > 0
+}
+
+func (s *Scorer) observedFor(name string) map[string]history.Average {
+	if s.history == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if o, ok := s.observed[name]; ok && s.clock.Now().Sub(o.loaded) < observedRefresh {
+		return o.averages
+	}
+	averages, err := s.history.Averages(name, s.clock.Now().Add(-observedWindow))
+	if err != nil {
+		//estimates still work without history
+		averages = nil
+	}
+	s.observed[name] = observation{loaded: s.clock.Now(), averages: averages}
+	return averages
+}
+
+func score(c Candidate, o Objective) float64 {
+	xpWeight := o.XpWeight
+	if len(o.Skills) > 0 && !slices.Contains(o.Skills, c.Skill) {
+		xpWeight = 0
+	}
+	return xpWeight*c.XpPerSecond() + o.GoldWeight*c.ValuePerSecond()
+}
+
+// estimateXp gives no xp once the character is more than xpLevelRange levels above the target
+func estimateXp(characterLevel, targetLevel int) float64 {
+	if characterLevel-targetLevel > xpLevelRange {
+		return 0
+	}
+	return float64(baseXpPerAction + targetLevel*xpPerLevel)
+}
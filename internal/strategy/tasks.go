@@ -0,0 +1,168 @@
+package strategy
+
+import (
+	"artifactsmmo/internal/player"
+	"artifactsmmo/internal/world"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"math"
+	"time"
+)
+
+// TaskCoin is the item the taskmaster rewards and charges for cancelling
+const TaskCoin = "tasks_coin"
+
+const (
+	defaultTaskMaxDuration   = 2 * time.Hour
+	defaultExchangeThreshold = 10
+	defaultCoinReserve       = 3
+	taskCancelCost           = 1
+	taskExchangeCost         = 3
+)
+
+type TaskDecision int
+
+const (
+	// DoTask means the returned candidate completes the task
+	DoTask TaskDecision = iota
+	// CancelTask means the task costs too much and coins are available to cancel it
+	CancelTask
+	// SkipTask means the task should be cancelled but there are no coins, do something else meanwhile
+	SkipTask
+)
+
+func (d TaskDecision) String() string {
+	switch d {
+	case DoTask:
+		return "do"
+	case CancelTask:
+		return "cancel"
+	case SkipTask:
+		return "skip"
+	default:
+		return ""
+	}
+}
+
+// TaskPolicy decides whether a task is worth doing based on the scorer's estimates
+type TaskPolicy struct {
+	// MaxDuration is the longest a task may take before it is cancelled
+	MaxDuration time.Duration `yaml:"max_duration" mapstructure:"max_duration"`
+	// ExchangeThreshold is the number of coins across inventory and bank before they are exchanged, nil uses the default
+	ExchangeThreshold *int `yaml:"exchange_threshold" mapstructure:"exchange_threshold"`
+	// CoinReserve is kept back from exchanges to pay for cancellations, nil uses the default and 0 keeps nothing back
+	CoinReserve *int `yaml:"coin_reserve" mapstructure:"coin_reserve"`
+}
+
+func DefaultTaskPolicy() TaskPolicy {
+	threshold, reserve := defaultExchangeThreshold, defaultCoinReserve
+	return TaskPolicy{
+		MaxDuration:       defaultTaskMaxDuration,
+		ExchangeThreshold: &threshold,
+		CoinReserve:       &reserve,
+	}
+}
+
+// WithDefaults fills unset fields from DefaultTaskPolicy
+func (t TaskPolicy) WithDefaults() TaskPolicy {
+	d := DefaultTaskPolicy()
+	if t.MaxDuration <= 0 {
+		t.MaxDuration = d.MaxDuration
+	}
+	if t.ExchangeThreshold == nil {
+		t.ExchangeThreshold = d.ExchangeThreshold
+	}
+	if t.CoinReserve == nil {
+		t.CoinReserve = d.CoinReserve
+	}
+	return t
+}
+
+// EstimateTask returns the candidate that progresses the player's task and the estimated time left.
+// ok is false when the task cannot be done, e.g. an unwinnable monster, a resource above the skill level or any task that is not monsters or resources.
+func (s *Scorer) EstimateTask(p *player.Player) (Candidate, time.Duration, bool) {
+	task := p.Data().Task
+	if task == nil {
+		return Candidate{}, 0, false
+	}
+
+	//crafts tasks need crafting and this api version has no items trade flow, so only monsters and resources tasks are doable
+	var want CandidateType
+	switch client.TaskSchemaType(task.Type) {
+	case client.Monsters:
+		want = FightCandidate
+	case client.Resources:
+		want = GatherCandidate
+	default:
+		return Candidate{}, time.Duration(math.MaxInt64), false
+	}
+
+	remaining := task.Total - task.Progress
+	best, bestSeconds, found := Candidate{}, math.Inf(1), false
+	for _, c := range s.Candidates(p) {
+		if c.Type != want {
+			continue
+		}
+		//monsters tasks count kills, items tasks count drops which only come with some actions
+		actions := float64(remaining)
+		if c.Code != task.Code {
+			perAction := c.Drops[task.Code]
+			if perAction <= 0 {
+				continue
+			}
+			actions /= perAction
+		}
+		seconds := c.TravelSeconds + actions*(c.ActionSeconds+c.BankSeconds)
+		if seconds < bestSeconds {
+			c.Quantity = max(1, min(c.Quantity, int(math.Ceil(actions))))
+			best, bestSeconds, found = c, seconds, true
+		}
+	}
+	if !found {
+		return Candidate{}, time.Duration(math.MaxInt64), false
+	}
+	return best, time.Duration(bestSeconds * float64(time.Second)), true
+}
+
+// Decide returns what to do about the player's current task, the candidate is only set for DoTask
+func (s *Scorer) Decide(p *player.Player, policy TaskPolicy) (TaskDecision, Candidate) {
+	c, duration, ok := s.EstimateTask(p)
+	if ok && duration <= policy.WithDefaults().MaxDuration {
+		return DoTask, c
+	}
+
+	if s.TaskCoins(p) >= taskCancelCost {
+		return CancelTask, Candidate{}
+	}
+	return SkipTask, Candidate{}
+}
+
+// ShouldExchange reports whether the player should exchange coins now and how many coins to withdraw from the bank first
+func (s *Scorer) ShouldExchange(p *player.Player, policy TaskPolicy) (bool, int) {
+	policy = policy.WithDefaults()
+	inventory := p.CheckInventory(TaskCoin)
+	total := inventory + BankQuantity(s.world, p.Account, TaskCoin)
+	if total < *policy.ExchangeThreshold || total-taskExchangeCost < *policy.CoinReserve {
+		return false, 0
+	}
+	return true, max(0, taskExchangeCost-inventory)
+}
+
+// TaskCoins counts the coins in the player's inventory and the bank
+func (s *Scorer) TaskCoins(p *player.Player) int {
+	return p.CheckInventory(TaskCoin) + BankQuantity(s.world, p.Account, TaskCoin)
+}
+
+// BankQuantity counts the item in the bank of the account
+func BankQuantity(w *world.Collector, account, code string) int {
+	bank, ok := w.Bank(account)
+	if !ok {
+		return 0
+	}
+	qty := 0
+	for _, i := range bank.Items() {
+		if i.Code == code {
+			qty += i.Quantity
+		}
+	}
+	return qty
+}
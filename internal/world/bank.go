@@ -0,0 +1,125 @@
+package world
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/events"
+	"context"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// Bank is the bank of one account, every account has its own while the rest of the world is shared
+type Bank struct {
+	Account string
+	items   []client.SimpleItemSchema
+	details client.BankSchema
+	mu      sync.RWMutex
+	ctx     context.Context
+	client  api.WorldAPI
+	bus     *events.Bus
+	clock   clock.Clock
+	logger  *slog.Logger
+}
+
+func NewBank(ctx context.Context, account string, c api.WorldAPI, bus *events.Bus, clk clock.Clock) *Bank {
+	return &Bank{
+		Account: account,
+		ctx:     ctx,
+		client:  c,
+		bus:     bus,
+		clock:   clk,
+		logger:  slog.Default().With("source", "bank", "account", account),
+	}
+}
+
+// Load reads the items and the details of the bank from the api
+func (b *Bank) Load() error {
+	if err := b.LoadItems(); err != nil {
+		return fmt.Errorf("load bank items: %w", err)
+	}
+	if err := b.LoadDetails(); err != nil {
+		return fmt.Errorf("load bank gold: %w", err)
+	}
+	return nil
+}
+
+func (b *Bank) LoadItems() error {
+	b.logger.Info("Loading Bank Items")
+	data := make([]client.SimpleItemSchema, 0)
+	size := 100
+	for page := 1; ; page++ {
+		resp, err := b.client.GetBankItemsMyBankItemsGetWithResponse(b.ctx, &client.GetBankItemsMyBankItemsGetParams{
+			ItemCode: nil,
+			Page:     &page,
+			Size:     &size,
+		})
+		if err != nil {
+			return fmt.Errorf("get all bank items: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return fmt.Errorf("get all bank items: %d", resp.StatusCode())
+		}
+
+		data = append(data, resp.JSON200.Data...)
+		if p, err := resp.JSON200.Page.AsDataPageSimpleItemSchemaPage0(); err != nil {
+			return fmt.Errorf("get all bank items: %w", err)
+		} else if page >= p {
+			break
+		}
+	}
+
+	b.UpdateItems(data)
+
+	return nil
+}
+
+func (b *Bank) LoadDetails() error {
+	b.logger.Info("Loading Bank Details")
+	resp, err := b.client.GetBankDetailsMyBankGetWithResponse(b.ctx)
+	if err != nil {
+		return fmt.Errorf("get bank details: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("get bank details: %d", resp.StatusCode())
+	}
+
+	b.UpdateDetails(resp.JSON200.Data)
+	b.bus.Publish(events.BankChanged{Meta: events.NewMeta("", b.clock), Account: b.Account, Gold: &resp.JSON200.Data.Gold})
+
+	return nil
+}
+
+func (b *Bank) Items() []client.SimpleItemSchema {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return slices.Clone(b.items)
+}
+
+func (b *Bank) Details() client.BankSchema {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.details
+}
+
+func (b *Bank) UpdateGold(q int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.details.Gold = q
+}
+
+func (b *Bank) UpdateItems(schema []client.SimpleItemSchema) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = schema
+}
+
+func (b *Bank) UpdateDetails(details client.BankSchema) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.details = details
+}
@@ -1,59 +1,55 @@
 package world
 
 import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/events"
 	"artifactsmmo/internal/models"
 	"context"
 	"fmt"
-	"github.com/promiseofcake/artifactsmmo-go-client/client"
 	"github.com/sagikazarmark/slog-shim"
-	"net/http"
 	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Collector struct {
-	Resources   ResourceMap
-	tiles       []models.MapTile
-	Monsters    []models.Monster
-	bankItems   []client.SimpleItemSchema
-	bankDetails client.BankSchema
-	mu          sync.RWMutex
-	ctx         context.Context
-	client      *client.ClientWithResponses
-	Out         chan error
-	logger      *slog.Logger
-	BankChannel chan models.BankResponse
+	resourceData atomic.Pointer[ResourceMap]
+	mapData      atomic.Pointer[mapSnapshot]
+	monsterData  atomic.Pointer[monsterSnapshot]
+	eventData    atomic.Pointer[[]models.GameEvent]
+	banks        map[string]*Bank
+	prices       Prices
+	mu           sync.RWMutex
+	ctx          context.Context
+	client       api.WorldAPI
+	Out          chan error
+	logger       *slog.Logger
+	bus          *events.Bus
+	clock        clock.Clock
+	// replaced holds the tiles event content was put over, they come back when the event ends
+	replaced map[models.TileID]models.MapTile
 }
 
-func NewCollector(ctx context.Context, c *client.ClientWithResponses) (*Collector, error) {
+func NewCollector(ctx context.Context, c api.WorldAPI, bus *events.Bus, clk clock.Clock, cache Cache) (*Collector, error) {
 	collector := &Collector{
-		ctx:         ctx,
-		client:      c,
-		Out:         make(chan error),
-		BankChannel: make(chan models.BankResponse),
-		logger:      slog.Default().With("source", "collector"),
+		ctx:      ctx,
+		client:   c,
+		Out:      make(chan error),
+		bus:      bus,
+		clock:    clk,
+		banks:    map[string]*Bank{},
+		replaced: map[models.TileID]models.MapTile{},
+		logger:   slog.Default().With("source", "collector"),
 	}
 	collector.logger.Info("Loading World")
-	rData, err := collector.getAllResources(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("get all resources: %w", err)
+	if err := collector.loadStatic(cache); err != nil {
+		return nil, fmt.Errorf("load world data: %w", err)
 	}
-	collector.Resources = rData
 
-	if err = collector.loadMapTiles(); err != nil {
-		return nil, fmt.Errorf("update world data: %w", err)
-	}
-
-	if err = collector.LoadBankItems(); err != nil {
-		return nil, fmt.Errorf("load bank items: %w", err)
-	}
-
-	if err = collector.LoadBankDetails(); err != nil {
-		return nil, fmt.Errorf("load bank gold: %w", err)
-	}
-
-	if err = collector.loadMonsters(); err != nil {
-		return nil, fmt.Errorf("load monsters: %w", err)
+	if err := collector.loadEvents(); err != nil {
+		return nil, fmt.Errorf("load events: %w", err)
 	}
 
 	collector.start()
@@ -62,88 +58,63 @@ func NewCollector(ctx context.Context, c *client.ClientWithResponses) (*Collecto
 }
 
 func (w *Collector) start() {
-	go func() {
-		for {
-			select {
-			case <-w.ctx.Done():
-				return
-			case data := <-w.BankChannel:
-				if data.Gold != nil {
-					w.UpdateBankGold(*data.Gold)
-				}
-				if data.Items != nil {
-					w.UpdateBankItems(*data.Items)
-				}
-			}
+	events.Handle(w.ctx, w.bus, func(e events.BankChanged) {
+		bank, ok := w.Bank(e.Account)
+		if !ok {
+			w.logger.Warn("bank changed for an unknown account", "account", e.Account)
+			return
 		}
-	}()
-}
-
-func (w *Collector) LoadBankItems() error {
-	w.logger.Info("Loading Bank Items")
-	data := make([]client.SimpleItemSchema, 0)
-	size := 100
-	for page := 1; ; page++ {
-		resp, err := w.client.GetBankItemsMyBankItemsGetWithResponse(w.ctx, &client.GetBankItemsMyBankItemsGetParams{
-			ItemCode: nil,
-			Page:     &page,
-			Size:     &size,
-		})
-		if err != nil {
-			return fmt.Errorf("get all bank items: %w", err)
+		if e.Gold != nil {
+			bank.UpdateGold(*e.Gold)
 		}
-		if resp.StatusCode() != http.StatusOK {
-			return fmt.Errorf("get all bank items: %d", resp.StatusCode())
+		if e.Items != nil {
+			bank.UpdateItems(*e.Items)
 		}
-
-		data = append(data, resp.JSON200.Data...)
-		if p, err := resp.JSON200.Page.AsDataPageSimpleItemSchemaPage0(); err != nil {
-			return fmt.Errorf("get all bank items: %w", err)
-		} else if page >= p {
-			break
-		}
-	}
-
-	w.UpdateBankItems(data)
-
-	return nil
+	})
 }
 
-func (w *Collector) LoadBankDetails() error {
-	w.logger.Info("Loading Bank Details")
-	resp, err := w.client.GetBankDetailsMyBankGetWithResponse(w.ctx)
-	if err != nil {
-		return fmt.Errorf("get all bank items: %w", err)
-	}
-	if resp.StatusCode() != http.StatusOK {
-		return fmt.Errorf("get all bank items: %d", resp.StatusCode())
+// AddAccount loads the bank of an account, its players' bank actions are routed to it by the account in BankChanged
+func (w *Collector) AddAccount(account string, c api.WorldAPI) error {
+	bank := NewBank(w.ctx, account, c, w.bus, w.clock)
+	w.mu.Lock()
+	if _, ok := w.banks[account]; ok {
+		w.mu.Unlock()
+		return fmt.Errorf("account %s added twice", account)
 	}
+	w.banks[account] = bank
+	w.mu.Unlock()
 
-	w.UpdateBankDetails(resp.JSON200.Data)
-
+	if err := bank.Load(); err != nil {
+		return fmt.Errorf("load bank of account %s: %w", account, err)
+	}
 	return nil
 }
 
-func (w *Collector) UpdateBankGold(q int) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.bankDetails.Gold = q
-}
-
-func (w *Collector) UpdateBankItems(schema []client.SimpleItemSchema) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.bankItems = schema
+// Bank is the bank of the account
+func (w *Collector) Bank(account string) (*Bank, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	b, ok := w.banks[account]
+	return b, ok
 }
 
-func (w *Collector) UpdateBankDetails(details client.BankSchema) {
-	w.mu.Lock()
-	defer w.mu.Unlock()
-	w.bankDetails = details
+// Banks are the banks of all accounts sorted by account
+func (w *Collector) Banks() []*Bank {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	banks := make([]*Bank, 0, len(w.banks))
+	for _, b := range w.banks {
+		banks = append(banks, b)
+	}
+	slices.SortFunc(banks, func(a, b *Bank) int {
+		return strings.Compare(a.Account, b.Account)
+	})
+	return banks
 }
 
 func (w *Collector) GetResourceByName(name string) *Resource {
-	if r, ok := w.Resources[name]; ok {
+	if r, ok := w.resources()[name]; ok {
+		r = r.clone()
 		return &r
 	}
 	return nil
@@ -153,9 +124,9 @@ func (w *Collector) GetResourceByName(name string) *Resource {
 func (w *Collector) GetResourcesBySkill(skill string, level int) []Resource {
 	data := make([]Resource, 0)
 
-	for _, r := range w.Resources {
+	for _, r := range w.resources() {
 		if r.Skill == skill && r.Level <= level {
-			data = append(data, r)
+			data = append(data, r.clone())
 		}
 	}
 
@@ -166,14 +137,7 @@ func (w *Collector) GetResourcesBySkill(skill string, level int) []Resource {
 	return data
 }
 
-func (w *Collector) GetMapByContentType(contentType mapContentType) []*models.MapTile {
-	cString := contentType.String()
-
-	res := make([]*models.MapTile, 0)
-	for _, m := range w.MapTiles() {
-		if m.Type == cString {
-			res = append(res, &m)
-		}
-	}
-	return res
+// GetMapByContentType returns a copy of the tiles with the content type
+func (w *Collector) GetMapByContentType(contentType MapContentType) []models.MapTile {
+	return slices.Clone(w.mapSnapshot().byType[contentType.String()])
 }
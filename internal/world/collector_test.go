@@ -0,0 +1,34 @@
+package world
+
+import (
+	"artifactsmmo/internal/models"
+	"testing"
+
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+)
+
+func TestLookupsReturnCopies(t *testing.T) {
+	w := &Collector{}
+	w.storeMonsters([]client.MonsterSchema{{Code: "chicken", Name: "Chicken", Level: 1, ResFire: 10, Drops: []client.DropRateSchema{{Code: "feather", Rate: 2}}}})
+	w.storeResources([]client.ResourceSchema{{Code: "ash_tree", Name: "Ash Tree", Skill: "woodcutting", Level: 1, Drops: []client.DropRateSchema{{Code: "ash_wood", Rate: 1}}}})
+	b := &Bank{items: []client.SimpleItemSchema{{Code: "copper_ore", Quantity: 5}}}
+
+	m, _ := w.GetMonster("chicken")
+	m.Resistances[models.Fire] = 50
+	m.Drops[0].Code = "egg"
+	w.Monsters()[0].Resistances[models.Fire] = 50
+	if m, _ := w.GetMonster("chicken"); m.Resistances[models.Fire] != 10 || m.Drops[0].Code != "feather" {
+		t.Errorf("monster changed through a returned copy: %+v", m)
+	}
+
+	w.GetResourceByName("ash_tree").Drops[0].Code = "birch_wood"
+	w.GetResourcesBySkill("woodcutting", 1)[0].Drops[0].Rate = 5
+	if r := w.GetResourceByName("ash_tree"); r.Drops[0] != (client.DropRateSchema{Code: "ash_wood", Rate: 1}) {
+		t.Errorf("resource changed through a returned copy: %+v", r.Drops)
+	}
+
+	b.Items()[0].Quantity = 1
+	if got := b.Items()[0].Quantity; got != 5 {
+		t.Errorf("bank holds %d copper_ore after changing a returned copy, want 5", got)
+	}
+}
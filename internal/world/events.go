@@ -0,0 +1,186 @@
+package world
+
+import (
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+	"slices"
+)
+
+func (w *Collector) loadEvents() error {
+	w.logger.Info("Loading Events")
+	data := make([]models.GameEvent, 0)
+	size := 100
+
+	for page := 1; ; page++ {
+		resp, err := w.client.GetAllEventsEventsGetWithResponse(w.ctx, &client.GetAllEventsEventsGetParams{
+			Page: &page,
+			Size: &size,
+		})
+		if err != nil {
+			return fmt.Errorf("get all events: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return fmt.Errorf("get all events: %d", resp.StatusCode())
+		}
+		for _, e := range resp.JSON200.Data {
+			tile, ok := tileFromSchema(e.Map)
+			if !ok {
+				continue
+			}
+			data = append(data, models.GameEvent{Name: e.Name, Tile: tile, Expiration: e.Expiration})
+		}
+
+		if resp.JSON200.Pages == nil {
+			break
+		}
+		if p, pErr := resp.JSON200.Pages.AsDataPageActiveEventSchemaPages0(); pErr != nil {
+			return fmt.Errorf("get all events: %w", pErr)
+		} else if page >= p {
+			break
+		}
+	}
+
+	prev := w.eventData.Swap(&data)
+	//the cached or last refreshed map does not have the content of events that started since
+	var before []models.GameEvent
+	if prev != nil {
+		before = *prev
+	}
+	started, ended := diffEvents(before, data)
+	for _, e := range started {
+		w.addEventTile(e)
+	}
+	for _, e := range ended {
+		w.removeEventTile(e)
+	}
+	if prev == nil {
+		return nil
+	}
+	for _, e := range started {
+		w.bus.Publish(events.GameEventStarted{Meta: events.NewMeta("", w.clock), Event: e})
+	}
+	for _, e := range ended {
+		w.bus.Publish(events.GameEventEnded{Meta: events.NewMeta("", w.clock), Event: e})
+	}
+	return nil
+}
+
+func diffEvents(prev, next []models.GameEvent) (started, ended []models.GameEvent) {
+	key := func(e models.GameEvent) string {
+		return fmt.Sprintf("%s %d,%d", e.Name, e.Tile.X, e.Tile.Y)
+	}
+	before := make(map[string]bool, len(prev))
+	for _, e := range prev {
+		before[key(e)] = true
+	}
+	after := make(map[string]bool, len(next))
+	for _, e := range next {
+		after[key(e)] = true
+		if !before[key(e)] {
+			started = append(started, e)
+		}
+	}
+	for _, e := range prev {
+		if !after[key(e)] {
+			ended = append(ended, e)
+		}
+	}
+	return started, ended
+}
+
+// addEventTile puts the event's content on the map until the event ends
+func (w *Collector) addEventTile(e models.GameEvent) {
+	id := e.Tile.ID()
+	w.updateMap(func(m *mapSnapshot) []models.MapTile {
+		tiles := make([]models.MapTile, 0, len(m.tiles)+1)
+		for _, t := range m.tiles {
+			if t.ID() != id {
+				tiles = append(tiles, t)
+			}
+		}
+		if old, ok := m.byID[id]; ok && old != e.Tile {
+			w.mu.Lock()
+			w.replaced[id] = old
+			w.mu.Unlock()
+		}
+		return append(tiles, e.Tile)
+	})
+}
+
+// removeEventTile takes the event's content off the map and puts back what it replaced.
+// A map refresh that already dropped the content is left alone.
+func (w *Collector) removeEventTile(e models.GameEvent) {
+	id := e.Tile.ID()
+	w.mu.Lock()
+	old, replaced := w.replaced[id]
+	delete(w.replaced, id)
+	w.mu.Unlock()
+
+	w.updateMap(func(m *mapSnapshot) []models.MapTile {
+		if m.byID[id] != e.Tile {
+			return m.tiles
+		}
+		tiles := make([]models.MapTile, 0, len(m.tiles))
+		for _, t := range m.tiles {
+			if t.ID() != id {
+				tiles = append(tiles, t)
+			}
+		}
+		if replaced {
+			tiles = append(tiles, old)
+		}
+		return tiles
+	})
+}
+
+// expiredEvent tells if the tile is the content of an event that is over but still listed until the next refresh
+func (w *Collector) expiredEvent(t models.MapTile) bool {
+	data := w.eventData.Load()
+	if data == nil {
+		return false
+	}
+	now := w.clock.Now()
+	for _, e := range *data {
+		if e.Tile == t && !e.Active(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutExpiredEvents drops the tiles of events that are over, the slice is returned as is when there are none
+func (w *Collector) withoutExpiredEvents(tiles []models.MapTile) []models.MapTile {
+	if !slices.ContainsFunc(tiles, w.expiredEvent) {
+		return tiles
+	}
+	return slices.DeleteFunc(slices.Clone(tiles), w.expiredEvent)
+}
+
+// ActiveEvents returns the events that have not expired yet
+func (w *Collector) ActiveEvents() []models.GameEvent {
+	data := w.eventData.Load()
+	if data == nil {
+		return nil
+	}
+	now := w.clock.Now()
+	res := make([]models.GameEvent, 0, len(*data))
+	for _, e := range *data {
+		if e.Active(now) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// EventAt returns the active event on the tile, false when the tile has no event
+func (w *Collector) EventAt(id models.TileID) (models.GameEvent, bool) {
+	for _, e := range w.ActiveEvents() {
+		if e.Tile.ID() == id {
+			return e, true
+		}
+	}
+	return models.GameEvent{}, false
+}
@@ -0,0 +1,67 @@
+package world
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+)
+
+// stubEvents answers the events call with a single page that has no page count, like the api may when nothing is active
+type stubEvents struct {
+	api.WorldAPI
+	events []client.ActiveEventSchema
+}
+
+func (s *stubEvents) GetAllEventsEventsGetWithResponse(_ context.Context, _ *client.GetAllEventsEventsGetParams, _ ...client.RequestEditorFn) (*client.GetAllEventsEventsGetResponse, error) {
+	return &client.GetAllEventsEventsGetResponse{
+		HTTPResponse: &http.Response{StatusCode: http.StatusOK},
+		JSON200:      &client.DataPageActiveEventSchema{Data: s.events},
+	}, nil
+}
+
+func TestLoadEventsWithoutPages(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	var content client.MapSchema_Content
+	if err := content.FromMapContentSchema(client.MapContentSchema{Type: "monster", Code: "bandit_lizard"}); err != nil {
+		t.Fatal(err)
+	}
+	lizard := client.ActiveEventSchema{Name: "Bandit Camp", Map: client.MapSchema{X: 3, Y: 3, Content: content}, Expiration: clk.Now().Add(time.Hour)}
+
+	tests := []struct {
+		name   string
+		events []client.ActiveEventSchema
+		want   int
+	}{
+		{name: "no events"},
+		{name: "one event", events: []client.ActiveEventSchema{lizard}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &Collector{
+				ctx:      context.Background(),
+				client:   &stubEvents{events: tt.events},
+				bus:      events.NewBus(),
+				clock:    clk,
+				replaced: map[models.TileID]models.MapTile{},
+				logger:   slog.Default(),
+			}
+			if err := w.loadEvents(); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(*w.eventData.Load()); got != tt.want {
+				t.Errorf("%d events loaded, want %d", got, tt.want)
+			}
+			if _, ok := w.Tile(models.TileID{X: 3, Y: 3}); ok != (tt.want > 0) {
+				t.Errorf("event tile on the map %t, want %t", ok, tt.want > 0)
+			}
+		})
+	}
+}
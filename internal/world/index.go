@@ -0,0 +1,99 @@
+package world
+
+import (
+	"artifactsmmo/internal/models"
+	"cmp"
+	"math"
+	"slices"
+)
+
+// mapSnapshot is an immutable view of the map, a refresh builds a new snapshot and swaps it in.
+// Lookups work on whichever snapshot they loaded so they never see a half updated map.
+type mapSnapshot struct {
+	tiles  []models.MapTile
+	byType map[string][]models.MapTile
+	byCode map[string][]models.MapTile
+	byID   map[models.TileID]models.MapTile
+}
+
+func newMapSnapshot(tiles []models.MapTile) *mapSnapshot {
+	s := &mapSnapshot{
+		tiles:  tiles,
+		byType: map[string][]models.MapTile{},
+		byCode: map[string][]models.MapTile{},
+		byID:   make(map[models.TileID]models.MapTile, len(tiles)),
+	}
+	for _, t := range tiles {
+		s.byType[t.Type] = append(s.byType[t.Type], t)
+		s.byCode[t.Code] = append(s.byCode[t.Code], t)
+		s.byID[t.ID()] = t
+	}
+	return s
+}
+
+var emptyMap = newMapSnapshot(nil)
+
+func (w *Collector) mapSnapshot() *mapSnapshot {
+	if s := w.mapData.Load(); s != nil {
+		return s
+	}
+	return emptyMap
+}
+
+// Tile returns the content tile with the id, false when the tile has no content
+func (w *Collector) Tile(id models.TileID) (models.MapTile, bool) {
+	t, ok := w.mapSnapshot().byID[id]
+	return t, ok
+}
+
+// NearestTiles returns up to k tiles with the code ordered by distance from x, y
+func (w *Collector) NearestTiles(code string, x, y, k int) []models.MapTile {
+	tiles := slices.Clone(w.withoutExpiredEvents(w.mapSnapshot().byCode[code]))
+	slices.SortFunc(tiles, func(a, b models.MapTile) int {
+		return cmp.Compare(getDistance(x, y, a.X, a.Y), getDistance(x, y, b.X, b.Y))
+	})
+	if len(tiles) > k {
+		tiles = tiles[:k]
+	}
+	return tiles
+}
+
+func closestTile(tiles []models.MapTile, x, y int) (models.MapTile, bool) {
+	var closest models.MapTile
+	found := false
+	distance := math.MaxInt
+	for _, t := range tiles {
+		if d := getDistance(x, y, t.X, t.Y); d < distance {
+			closest = t
+			found = true
+			distance = d
+		}
+	}
+	return closest, found
+}
+
+// monsterSnapshot is swapped in whole like mapSnapshot
+type monsterSnapshot struct {
+	monsters []models.Monster
+	byCode   map[string]models.Monster
+}
+
+func newMonsterSnapshot(monsters []models.Monster) *monsterSnapshot {
+	s := &monsterSnapshot{
+		monsters: monsters,
+		byCode:   make(map[string]models.Monster, len(monsters)),
+	}
+	for _, m := range monsters {
+		s.byCode[m.Code] = m
+	}
+	return s
+}
+
+var emptyMonsters = newMonsterSnapshot(nil)
+
+func (w *Collector) monsterSnapshot() *monsterSnapshot {
+	if s := w.monsterData.Load(); s != nil {
+		return s
+	}
+	return emptyMonsters
+}
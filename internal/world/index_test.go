@@ -0,0 +1,121 @@
+package world
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"fmt"
+	"github.com/sagikazarmark/slog-shim"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// two maps a refresh swaps between, a lookup must see one of them whole and never a mix
+var (
+	mapA = []models.MapTile{
+		{X: 0, Y: 0, Type: "bank", Code: "bank"},
+		{X: 1, Y: 1, Type: "resource", Code: "copper_rocks"},
+		{X: 5, Y: 5, Type: "resource", Code: "copper_rocks"},
+		{X: 4, Y: 0, Type: "monster", Code: "chicken"},
+	}
+	mapB = []models.MapTile{
+		{X: 3, Y: 3, Type: "bank", Code: "bank"},
+		{X: 2, Y: 2, Type: "resource", Code: "copper_rocks"},
+		{X: 6, Y: 6, Type: "resource", Code: "copper_rocks"},
+		{X: 9, Y: 9, Type: "resource", Code: "copper_rocks"},
+		{X: 4, Y: 0, Type: "monster", Code: "wolf"},
+	}
+)
+
+func TestMapRefreshDuringLookups(t *testing.T) {
+	w := &Collector{bus: events.NewBus(), clock: clock.Real{}, logger: slog.Default()}
+	w.storeMap(mapA)
+
+	maps := []*mapSnapshot{newMapSnapshot(mapA), newMapSnapshot(mapB)}
+	ids := map[models.TileID]bool{}
+	for _, m := range maps {
+		for id := range m.byID {
+			ids[id] = true
+		}
+	}
+
+	var done atomic.Bool
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		for i := 0; !done.Load(); i++ {
+			if i%2 == 0 {
+				w.storeMap(mapB)
+			} else {
+				w.storeMap(mapA)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 100)
+	lookup := func(check func() string) {
+		defer wg.Done()
+		for i := 0; i < 2000; i++ {
+			if msg := check(); msg != "" {
+				errs <- msg
+				return
+			}
+		}
+	}
+
+	wg.Add(4)
+	go lookup(func() string {
+		tile, ok := w.FindClosestTile("copper_rocks", 0, 0)
+		for _, m := range maps {
+			if want, _ := closestTile(m.byCode["copper_rocks"], 0, 0); ok && tile == want {
+				return ""
+			}
+		}
+		return fmt.Sprintf("FindClosestTile returned %+v, not the closest tile of either map", tile)
+	})
+	go lookup(func() string {
+		tiles := w.NearestTiles("copper_rocks", 0, 0, 10)
+		for _, m := range maps {
+			want := m.byCode["copper_rocks"]
+			if slices.Equal(tiles, want) {
+				return ""
+			}
+		}
+		return "NearestTiles mixed maps"
+	})
+	go lookup(func() string {
+		banks := w.GetMapByContentType(BankMapContentType)
+		for _, m := range maps {
+			if slices.Equal(banks, m.byType["bank"]) {
+				return ""
+			}
+		}
+		return "GetMapByContentType mixed maps"
+	})
+	go lookup(func() string {
+		for id := range ids {
+			tile, ok := w.Tile(id)
+			if !ok {
+				continue
+			}
+			if tile.ID() != id {
+				return fmt.Sprintf("Tile returned %+v for %+v", tile, id)
+			}
+			if tile != maps[0].byID[id] && tile != maps[1].byID[id] {
+				return fmt.Sprintf("Tile returned %+v, which is in neither map", tile)
+			}
+		}
+		return ""
+	})
+
+	wg.Wait()
+	done.Store(true)
+	<-refreshed
+	close(errs)
+	for msg := range errs {
+		t.Error(msg)
+	}
+}
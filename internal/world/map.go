@@ -1,18 +1,20 @@
 package world
 
 import (
+	"artifactsmmo/internal/api"
 	"artifactsmmo/internal/models"
 	"context"
 	"fmt"
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
 	"math"
 	"net/http"
+	"slices"
 )
 
-type mapContentType int
+type MapContentType int
 
 const (
-	MonsterMapContentType mapContentType = iota
+	MonsterMapContentType MapContentType = iota
 	ResourceMapContentType
 	WorkshopMapContentType
 	BankMapContentType
@@ -20,7 +22,7 @@ const (
 	GrandExchangeContentType
 )
 
-func (m mapContentType) String() string {
+func (m MapContentType) String() string {
 	switch m {
 	case MonsterMapContentType:
 		return "monster"
@@ -39,12 +41,13 @@ func (m mapContentType) String() string {
 	}
 }
 
-func (w *Collector) updateMap(ctx context.Context) ([]models.MapTile, error) {
+func fetchMap(ctx context.Context, c api.WorldAPI) ([]models.MapTile, error) {
 	size := 100
 	data := make([]client.MapSchema, 0)
+	total := 0
 
 	for page := 1; ; page++ {
-		resp, err := w.client.GetAllMapsMapsGetWithResponse(ctx, &client.GetAllMapsMapsGetParams{
+		resp, err := c.GetAllMapsMapsGetWithResponse(ctx, &client.GetAllMapsMapsGetParams{
 			ContentType: nil,
 			ContentCode: nil,
 			Page:        &page,
@@ -58,61 +61,102 @@ func (w *Collector) updateMap(ctx context.Context) ([]models.MapTile, error) {
 			return nil, fmt.Errorf("error fetching map resources: %s", resp.Status())
 		}
 		data = append(data, resp.JSON200.Data...)
+		if t, tErr := resp.JSON200.Total.AsDataPageMapSchemaTotal0(); tErr == nil {
+			total = t
+		}
 
+		if resp.JSON200.Pages == nil {
+			break
+		}
 		if p, pErr := resp.JSON200.Pages.AsDataPageMapSchemaPages0(); pErr != nil {
-			return nil, err
+			return nil, fmt.Errorf("error fetching map resources: %w", pErr)
 		} else if page >= p {
 			break
 		}
 	}
+	//the map can not shrink while it is paged through, fewer tiles means pages were lost
+	if len(data) < total {
+		return nil, fmt.Errorf("error fetching map resources: got %d of %d tiles", len(data), total)
+	}
 
 	resources := make([]models.MapTile, 0, len(data))
 
 	for _, d := range data {
-		if contentMap, cErr := d.Content.AsMapContentSchema(); cErr == nil {
-			resources = append(resources, models.MapTile{
-				X:    d.X,
-				Y:    d.Y,
-				Type: contentMap.Type,
-				Code: contentMap.Code,
-			})
+		if tile, ok := tileFromSchema(d); ok {
+			resources = append(resources, tile)
 		}
 	}
 	return resources, nil
 }
 
+// tileFromSchema converts a map schema, false when the tile has no content
+func tileFromSchema(d client.MapSchema) (models.MapTile, bool) {
+	contentMap, err := d.Content.AsMapContentSchema()
+	if err != nil {
+		return models.MapTile{}, false
+	}
+	return models.MapTile{
+		X:    d.X,
+		Y:    d.Y,
+		Type: contentMap.Type,
+		Code: contentMap.Code,
+	}, true
+}
+
+// MapTiles returns a copy of every tile with content
 func (w *Collector) MapTiles() []models.MapTile {
-	w.mu.RLock()
-	defer w.mu.RUnlock()
-	return w.tiles
+	return slices.Clone(w.mapSnapshot().tiles)
 }
 
 func (w *Collector) loadMapTiles() error {
 	w.logger.Info("Loading Map")
-	resp, err := w.updateMap(w.ctx)
+	resp, err := fetchMap(w.ctx, w.client)
 	if err != nil {
 		return fmt.Errorf("get all resources: %w", err)
 	}
-	w.mu.Lock()
-	w.tiles = resp
-	w.mu.Unlock()
+	//an empty map would report every tile as gone, keep the previous one
+	if len(resp) == 0 {
+		return fmt.Errorf("get all resources: no tiles with content")
+	}
+	w.storeMap(resp)
 	return nil
 }
 
-func (w *Collector) FindClosestTile(code string, x int, y int) *models.MapTile {
-	var closest *models.MapTile
-	distance := math.MaxInt
-	for _, t := range w.MapTiles() {
-		if t.Code == code {
-			d := getDistance(x, y, t.X, t.Y)
-			if d < distance {
-				closest = &t
-				distance = d
-			}
+// storeMap swaps in a snapshot of the tiles and publishes what changed since the previous one
+func (w *Collector) storeMap(tiles []models.MapTile) {
+	next := newMapSnapshot(tiles)
+	if prev := w.mapData.Swap(next); prev != nil {
+		w.publishMapChanges(prev, next)
+	}
+}
+
+// updateMap swaps in a snapshot of the tiles built from the current one, it builds again if a refresh swapped in between
+func (w *Collector) updateMap(update func(m *mapSnapshot) []models.MapTile) {
+	for {
+		prev := w.mapData.Load()
+		cur := prev
+		if cur == nil {
+			cur = emptyMap
+		}
+		next := newMapSnapshot(update(cur))
+		if w.mapData.CompareAndSwap(prev, next) {
+			w.publishMapChanges(cur, next)
+			return
 		}
 	}
+}
+
+// FindClosestTile returns the tile with the code nearest to x, y, false when the code is not on the map
+func (w *Collector) FindClosestTile(code string, x int, y int) (models.MapTile, bool) {
+	return closestTile(w.withoutExpiredEvents(w.mapSnapshot().byCode[code]), x, y)
+}
+
+// MoveSecondsPerTile is the estimated move cooldown for every tile travelled
+const MoveSecondsPerTile = 5
 
-	return closest
+// TravelSeconds estimates the move cooldown between two positions
+func TravelSeconds(x1, y1, x2, y2 int) int {
+	return getDistance(x1, y1, x2, y2) * MoveSecondsPerTile
 }
 
 func getDistance(x1, y1, x2, y2 int) int {
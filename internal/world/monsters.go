@@ -1,8 +1,10 @@
 package world
 
 import (
+	"artifactsmmo/internal/api"
 	"artifactsmmo/internal/models"
 	"artifactsmmo/internal/player"
+	"context"
 	"fmt"
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
 	"net/http"
@@ -10,10 +12,27 @@ import (
 
 func (w *Collector) loadMonsters() error {
 	w.logger.Info("Loading Monsters")
-	data := make([]models.Monster, 0)
+	schemas, err := fetchMonsters(w.ctx, w.client)
+	if err != nil {
+		return err
+	}
+	w.storeMonsters(schemas)
+	return nil
+}
+
+func (w *Collector) storeMonsters(schemas []client.MonsterSchema) {
+	data := make([]models.Monster, 0, len(schemas))
+	for _, m := range schemas {
+		data = append(data, models.MonsterFromSchema(m))
+	}
+	w.monsterData.Store(newMonsterSnapshot(data))
+}
+
+func fetchMonsters(ctx context.Context, c api.WorldAPI) ([]client.MonsterSchema, error) {
+	data := make([]client.MonsterSchema, 0)
 
 	for page := 1; ; page++ {
-		resp, err := w.client.GetAllMonstersMonstersGetWithResponse(w.ctx, &client.GetAllMonstersMonstersGetParams{
+		resp, err := c.GetAllMonstersMonstersGetWithResponse(ctx, &client.GetAllMonstersMonstersGetParams{
 			MinLevel: nil,
 			MaxLevel: nil,
 			Drop:     nil,
@@ -21,24 +40,23 @@ func (w *Collector) loadMonsters() error {
 			Size:     nil,
 		})
 		if err != nil {
-			return fmt.Errorf("get all monsters: %w", err)
+			return nil, fmt.Errorf("get all monsters: %w", err)
 		}
 		if resp.StatusCode() != http.StatusOK {
-			return fmt.Errorf("get all monsters: %d", resp.StatusCode())
-		}
-		for _, m := range resp.JSON200.Data {
-			data = append(data, models.MonsterFromSchema(m))
+			return nil, fmt.Errorf("get all monsters: %d", resp.StatusCode())
 		}
+		data = append(data, resp.JSON200.Data...)
 
+		if resp.JSON200.Pages == nil {
+			break
+		}
 		if p, pErr := resp.JSON200.Pages.AsDataPageMonsterSchemaPages0(); pErr != nil {
-			return fmt.Errorf("get all monsters: %w", pErr)
+			return nil, fmt.Errorf("get all monsters: %w", pErr)
 		} else if page >= p {
 			break
 		}
 	}
-
-	w.Monsters = data
-	return nil
+	return data, nil
 }
 
 // FilterMonsters gets a slice of monsters the player can kill
@@ -56,20 +74,30 @@ func (w *Collector) FilterMonsters(p *player.Player) []models.Monster {
 	w.logger.Debug("top skill for monster filter", "skill", skill, "dmg", skillDmg)
 
 	monsters := make([]models.Monster, 0)
-	for _, m := range w.Monsters {
+	for _, m := range w.monsterSnapshot().monsters {
 		if p.CanWinFight(skill, m) {
-			monsters = append(monsters, m)
+			monsters = append(monsters, m.Clone())
 		}
 	}
 
 	return monsters
 }
 
-func (w *Collector) GetMonster(code string) *models.Monster {
-	for _, m := range w.Monsters {
-		if m.Code == code {
-			return &m
-		}
+// GetMonster looks up a monster by code, the returned value is a copy callers may keep or change
+func (w *Collector) GetMonster(code string) (models.Monster, bool) {
+	m, ok := w.monsterSnapshot().byCode[code]
+	return m.Clone(), ok
+}
+
+// Monsters returns a copy of every known monster
+func (w *Collector) Monsters() []models.Monster {
+	return cloneMonsters(w.monsterSnapshot().monsters)
+}
+
+func cloneMonsters(monsters []models.Monster) []models.Monster {
+	res := make([]models.Monster, 0, len(monsters))
+	for _, m := range monsters {
+		res = append(res, m.Clone())
 	}
-	return nil
+	return res
 }
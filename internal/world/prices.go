@@ -0,0 +1,70 @@
+package world
+
+import (
+	"artifactsmmo/internal/api"
+	"context"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+)
+
+// Prices are what the grand exchange pays per item code
+type Prices map[string]int
+
+func PricesOf(items []client.GEItemSchema) Prices {
+	prices := Prices{}
+	for _, i := range items {
+		if i.SellPrice != nil {
+			prices[i.Code] = *i.SellPrice
+		}
+	}
+	return prices
+}
+
+// ItemPrice is what the grand exchange pays for the item, 0 when it cannot be sold there
+func (p Prices) ItemPrice(code string) int {
+	return p[code]
+}
+
+func (w *Collector) storePrices(items []client.GEItemSchema) {
+	prices := PricesOf(items)
+
+	w.mu.Lock()
+	w.prices = prices
+	w.mu.Unlock()
+}
+
+func fetchItems(ctx context.Context, c api.WorldAPI) ([]client.GEItemSchema, error) {
+	data := make([]client.GEItemSchema, 0)
+	size := 100
+	for page := 1; ; page++ {
+		resp, err := c.GetAllGeItemsGeGetWithResponse(ctx, &client.GetAllGeItemsGeGetParams{
+			Page: &page,
+			Size: &size,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("get all ge items: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("get all ge items: %d", resp.StatusCode())
+		}
+		data = append(data, resp.JSON200.Data...)
+
+		if resp.JSON200.Pages == nil {
+			break
+		}
+		if p, pErr := resp.JSON200.Pages.AsDataPageGEItemSchemaPages0(); pErr != nil {
+			return nil, fmt.Errorf("get all ge items: %w", pErr)
+		} else if page >= p {
+			break
+		}
+	}
+	return data, nil
+}
+
+// ItemPrice is what the grand exchange pays for the item, 0 when it cannot be sold there
+func (w *Collector) ItemPrice(code string) int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.prices.ItemPrice(code)
+}
@@ -0,0 +1,134 @@
+package world
+
+import (
+	"artifactsmmo/internal/events"
+	"artifactsmmo/internal/models"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultMapRefresh      = 5 * time.Minute
+	defaultMonsterRefresh  = time.Hour
+	defaultResourceRefresh = time.Hour
+	defaultBankRefresh     = 5 * time.Minute
+	defaultEventRefresh    = time.Minute
+)
+
+// RefreshConfig sets how often the collector reloads world data, a negative interval disables the refresh
+type RefreshConfig struct {
+	Map       time.Duration `yaml:"map" mapstructure:"map"`
+	Monsters  time.Duration `yaml:"monsters" mapstructure:"monsters"`
+	Resources time.Duration `yaml:"resources" mapstructure:"resources"`
+	// Bank picks up changes made outside this process, e.g. from the website or another account
+	Bank time.Duration `yaml:"bank" mapstructure:"bank"`
+	// Events should be short, events only last a limited time
+	Events time.Duration `yaml:"events" mapstructure:"events"`
+}
+
+func DefaultRefreshConfig() RefreshConfig {
+	return RefreshConfig{
+		Map:       defaultMapRefresh,
+		Monsters:  defaultMonsterRefresh,
+		Resources: defaultResourceRefresh,
+		Bank:      defaultBankRefresh,
+		Events:    defaultEventRefresh,
+	}
+}
+
+// NoRefresh turns every refresh off, for worlds that do not change like a simulation
+func NoRefresh() RefreshConfig {
+	return RefreshConfig{Map: -1, Monsters: -1, Resources: -1, Bank: -1, Events: -1}
+}
+
+// WithDefaults fills unset intervals from DefaultRefreshConfig
+func (r RefreshConfig) WithDefaults() RefreshConfig {
+	d := DefaultRefreshConfig()
+	if r.Map == 0 {
+		r.Map = d.Map
+	}
+	if r.Monsters == 0 {
+		r.Monsters = d.Monsters
+	}
+	if r.Resources == 0 {
+		r.Resources = d.Resources
+	}
+	if r.Bank == 0 {
+		r.Bank = d.Bank
+	}
+	if r.Events == 0 {
+		r.Events = d.Events
+	}
+	return r
+}
+
+// StartRefresh reloads world data in the background until the collector's context is done.
+// Failed refreshes are logged and retried on the next tick, lookups keep using the previous data.
+func (w *Collector) StartRefresh(cfg RefreshConfig) {
+	cfg = cfg.WithDefaults()
+	w.every("map", cfg.Map, w.loadMapTiles)
+	w.every("monsters", cfg.Monsters, w.loadMonsters)
+	w.every("resources", cfg.Resources, w.loadResources)
+	w.every("events", cfg.Events, w.loadEvents)
+	w.every("bank", cfg.Bank, func() error {
+		//one account failing does not hold back the others
+		for _, b := range w.Banks() {
+			if err := b.Load(); err != nil {
+				w.logger.Warn("refresh failed", "data", "bank", "account", b.Account, "error", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (w *Collector) every(name string, interval time.Duration, refresh func() error) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-w.clock.After(interval):
+				if err := refresh(); err != nil {
+					w.logger.Warn("refresh failed", "data", name, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (w *Collector) loadResources() error {
+	w.logger.Info("Loading Resources")
+	data, err := fetchResources(w.ctx, w.client)
+	if err != nil {
+		return fmt.Errorf("get all resources: %w", err)
+	}
+	w.storeResources(data)
+	return nil
+}
+
+// publishMapChanges diffs two snapshots by tile id and publishes what appeared and disappeared
+func (w *Collector) publishMapChanges(prev, next *mapSnapshot) {
+	appeared, disappeared := diffTiles(prev, next)
+	if len(appeared) == 0 && len(disappeared) == 0 {
+		return
+	}
+	w.logger.Info("map changed", "appeared", len(appeared), "disappeared", len(disappeared))
+	w.bus.Publish(events.MapChanged{Meta: events.NewMeta("", w.clock), Appeared: appeared, Disappeared: disappeared})
+}
+
+func diffTiles(prev, next *mapSnapshot) (appeared, disappeared []models.MapTile) {
+	for _, t := range next.tiles {
+		if old, ok := prev.byID[t.ID()]; !ok || old != t {
+			appeared = append(appeared, t)
+		}
+	}
+	for _, t := range prev.tiles {
+		if cur, ok := next.byID[t.ID()]; !ok || cur != t {
+			disappeared = append(disappeared, t)
+		}
+	}
+	return appeared, disappeared
+}
@@ -1,55 +1,85 @@
 package world
 
 import (
+	"artifactsmmo/internal/api"
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"net/http"
+	"slices"
 )
 
 type ResourceMap map[string]Resource
 
-type ResourceResponse struct {
-	Data []Resource `json:"data"`
+type Resource struct {
+	Skill string
+	Name  string
+	Code  string
+	Level int
+	Drops []client.DropRateSchema
 }
 
-type Resource struct {
-	Skill string          `json:"skill"`
-	Name  string          `json:"name"`
-	Code  string          `json:"code"`
-	Level int             `json:"level"`
-	Drops []ResourceDrops `json:"drops"`
+// clone copies the drops, so the copy can be changed without touching the collector's resource
+func (r Resource) clone() Resource {
+	r.Drops = slices.Clone(r.Drops)
+	return r
 }
 
-type ResourceDrops struct {
-	Code string `json:"code"`
-	Rate int    `json:"rate"`
-	//min and max quantity
+func resourceFromSchema(r client.ResourceSchema) Resource {
+	return Resource{
+		Skill: string(r.Skill),
+		Name:  r.Name,
+		Code:  r.Code,
+		Level: r.Level,
+		Drops: r.Drops,
+	}
 }
 
-func (w *Collector) getAllResources(ctx context.Context) (ResourceMap, error) {
-	resp, err := w.client.GetAllResourcesResourcesGetWithResponse(ctx, &client.GetAllResourcesResourcesGetParams{
-		MinLevel: nil,
-		MaxLevel: nil,
-		Skill:    nil,
-		Drop:     nil,
-		Page:     nil,
-		Size:     nil,
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error getting all resources: %w", err)
+func (w *Collector) resources() ResourceMap {
+	if r := w.resourceData.Load(); r != nil {
+		return *r
 	}
+	return nil
+}
 
-	var rr ResourceResponse
-	if err := json.Unmarshal(resp.Body, &rr); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response body: %w", err)
+func (w *Collector) storeResources(schemas []client.ResourceSchema) {
+	data := make(ResourceMap, len(schemas))
+	for _, r := range schemas {
+		data[r.Code] = resourceFromSchema(r)
 	}
+	w.resourceData.Store(&data)
+}
+
+func fetchResources(ctx context.Context, c api.WorldAPI) ([]client.ResourceSchema, error) {
+	data := make([]client.ResourceSchema, 0)
+	size := 100
+
+	for page := 1; ; page++ {
+		resp, err := c.GetAllResourcesResourcesGetWithResponse(ctx, &client.GetAllResourcesResourcesGetParams{
+			MinLevel: nil,
+			MaxLevel: nil,
+			Skill:    nil,
+			Drop:     nil,
+			Page:     &page,
+			Size:     &size,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting all resources: %w", err)
+		}
+		if resp.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("error getting all resources: %d", resp.StatusCode())
+		}
+		data = append(data, resp.JSON200.Data...)
 
-	result := make(ResourceMap)
-	for _, d := range rr.Data {
-		result[d.Code] = d
+		if resp.JSON200.Pages == nil {
+			break
+		}
+		if p, pErr := resp.JSON200.Pages.AsDataPageResourceSchemaPages0(); pErr != nil {
+			return nil, fmt.Errorf("error getting all resources: %w", pErr)
+		} else if page >= p {
+			break
+		}
 	}
 
-	return result, nil
+	return data, nil
 }
@@ -0,0 +1,134 @@
+package world
+
+import (
+	"artifactsmmo/internal/models"
+	"math"
+)
+
+// exactRouteLimit is the most stops ordered by trying every order, larger plans use nearest neighbour and 2-opt
+const exactRouteLimit = 8
+
+// Route is an ordered list of stops and the estimated move cooldown to visit them all
+type Route struct {
+	Stops   []models.MapTile
+	Seconds int
+}
+
+// PlanRoute orders the stops to minimise the total move cooldown starting from x, y
+func PlanRoute(x, y int, stops []models.MapTile) Route {
+	if len(stops) == 0 {
+		return Route{Stops: []models.MapTile{}}
+	}
+
+	var order []int
+	if len(stops) <= exactRouteLimit {
+		order = exactOrder(x, y, stops)
+	} else {
+		order = twoOpt(x, y, stops, nearestNeighbourOrder(x, y, stops))
+	}
+
+	ordered := make([]models.MapTile, 0, len(stops))
+	for _, i := range order {
+		ordered = append(ordered, stops[i])
+	}
+	return Route{Stops: ordered, Seconds: RouteSeconds(x, y, ordered)}
+}
+
+// RouteSeconds estimates the move cooldown to visit the stops in the given order starting from x, y
+func RouteSeconds(x, y int, stops []models.MapTile) int {
+	total := 0
+	for _, s := range stops {
+		total += TravelSeconds(x, y, s.X, s.Y)
+		x, y = s.X, s.Y
+	}
+	return total
+}
+
+// ClosestByContentType finds the nearest tile of the content type, e.g. the bank to deposit at
+func (w *Collector) ClosestByContentType(contentType MapContentType, x, y int) (models.MapTile, bool) {
+	return closestTile(w.mapSnapshot().byType[contentType.String()], x, y)
+}
+
+func orderSeconds(x, y int, stops []models.MapTile, order []int) int {
+	total := 0
+	for _, i := range order {
+		total += TravelSeconds(x, y, stops[i].X, stops[i].Y)
+		x, y = stops[i].X, stops[i].Y
+	}
+	return total
+}
+
+// exactOrder tries every permutation, fine for the handful of stops a plan has
+func exactOrder(x, y int, stops []models.MapTile) []int {
+	order := make([]int, len(stops))
+	for i := range order {
+		order[i] = i
+	}
+	best := append([]int(nil), order...)
+	bestSeconds := orderSeconds(x, y, stops, order)
+
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(order) {
+			if s := orderSeconds(x, y, stops, order); s < bestSeconds {
+				bestSeconds = s
+				copy(best, order)
+			}
+			return
+		}
+		for i := k; i < len(order); i++ {
+			order[k], order[i] = order[i], order[k]
+			permute(k + 1)
+			order[k], order[i] = order[i], order[k]
+		}
+	}
+	permute(0)
+
+	return best
+}
+
+func nearestNeighbourOrder(x, y int, stops []models.MapTile) []int {
+	visited := make([]bool, len(stops))
+	order := make([]int, 0, len(stops))
+	for len(order) < len(stops) {
+		next, distance := -1, math.MaxInt
+		for i, s := range stops {
+			if visited[i] {
+				continue
+			}
+			if d := getDistance(x, y, s.X, s.Y); d < distance {
+				next, distance = i, d
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		x, y = stops[next].X, stops[next].Y
+	}
+	return order
+}
+
+// twoOpt reverses segments of the order while that shortens the route
+func twoOpt(x, y int, stops []models.MapTile, order []int) []int {
+	best := orderSeconds(x, y, stops, order)
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(order)-1; i++ {
+			for j := i + 1; j < len(order); j++ {
+				reverse(order, i, j)
+				if s := orderSeconds(x, y, stops, order); s < best {
+					best = s
+					improved = true
+				} else {
+					reverse(order, i, j)
+				}
+			}
+		}
+	}
+	return order
+}
+
+func reverse(order []int, i, j int) {
+	for ; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
@@ -0,0 +1,139 @@
+package world
+
+import (
+	"artifactsmmo/internal/api"
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotVersion changes whenever the snapshot format does, older files are refused
+const SnapshotVersion = 1
+
+// Snapshot is the static world data as the api returns it, for offline tools like the simulator
+type Snapshot struct {
+	Version   int                     `json:"version"`
+	Created   time.Time               `json:"created"`
+	Tiles     []models.MapTile        `json:"tiles"`
+	Monsters  []client.MonsterSchema  `json:"monsters"`
+	Resources []client.ResourceSchema `json:"resources"`
+	Items     []client.GEItemSchema   `json:"items"`
+}
+
+// DownloadSnapshot fetches every page of static world data, stamped with the time of clk
+func DownloadSnapshot(ctx context.Context, c api.WorldAPI, clk clock.Clock) (Snapshot, error) {
+	s := Snapshot{Version: SnapshotVersion, Created: clk.Now()}
+	var err error
+	if s.Tiles, err = fetchMap(ctx, c); err != nil {
+		return s, err
+	}
+	if s.Monsters, err = fetchMonsters(ctx, c); err != nil {
+		return s, err
+	}
+	if s.Resources, err = fetchResources(ctx, c); err != nil {
+		return s, err
+	}
+	if s.Items, err = fetchItems(ctx, c); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// Save writes the snapshot as json, creating the directory if needed
+func (s Snapshot) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	//write then rename so a reader never sees half a file
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+func LoadSnapshot(path string) (Snapshot, error) {
+	var s Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, fmt.Errorf("read snapshot: %w", err)
+	}
+	if err = json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("decode snapshot %s: %w", path, err)
+	}
+	if s.Version != SnapshotVersion {
+		return s, fmt.Errorf("snapshot %s has version %d, want %d", path, s.Version, SnapshotVersion)
+	}
+	return s, nil
+}
+
+// Cache keeps the static world data in a snapshot file, starts within the ttl read it instead of downloading.
+// Bank and events change too often and are always loaded from the api.
+type Cache struct {
+	Path string
+	// TTL is how old a snapshot may be to be reused, zero always downloads and rewrites the file
+	TTL time.Duration
+}
+
+// loadStatic fills the map, monsters, resources and prices from the cache when it is fresh, otherwise downloads and caches them
+func (w *Collector) loadStatic(cache Cache) error {
+	if s, ok := w.cachedSnapshot(cache); ok {
+		w.storeSnapshot(s)
+		return nil
+	}
+
+	w.logger.Info("Downloading World")
+	s, err := DownloadSnapshot(w.ctx, w.client, w.clock)
+	if err != nil {
+		return err
+	}
+	w.storeSnapshot(s)
+	if cache.Path != "" {
+		if err = s.Save(cache.Path); err != nil {
+			w.logger.Warn("could not cache world snapshot", "path", cache.Path, "error", err)
+		}
+	}
+	return nil
+}
+
+func (w *Collector) cachedSnapshot(cache Cache) (Snapshot, bool) {
+	if cache.Path == "" || cache.TTL <= 0 {
+		return Snapshot{}, false
+	}
+	s, err := LoadSnapshot(cache.Path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			w.logger.Warn("ignoring cached world snapshot", "path", cache.Path, "error", err)
+		}
+		return Snapshot{}, false
+	}
+	age := w.clock.Now().Sub(s.Created)
+	if age > cache.TTL {
+		w.logger.Info("cached world snapshot expired", "path", cache.Path, "age", age.Round(time.Minute))
+		return Snapshot{}, false
+	}
+	w.logger.Info("Loading World from snapshot", "path", cache.Path, "age", age.Round(time.Minute))
+	return s, true
+}
+
+func (w *Collector) storeSnapshot(s Snapshot) {
+	w.mapData.Store(newMapSnapshot(s.Tiles))
+	w.storeMonsters(s.Monsters)
+	w.storeResources(s.Resources)
+	w.storePrices(s.Items)
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"artifactsmmo/internal/clock"
 	"artifactsmmo/internal/engine"
+	"artifactsmmo/internal/history"
+	"artifactsmmo/internal/ratelimit"
+	"artifactsmmo/internal/replay"
+	"artifactsmmo/internal/server"
+	"artifactsmmo/internal/strategy"
+	"artifactsmmo/internal/world"
 	"context"
 	"fmt"
 	"github.com/sagikazarmark/slog-shim"
@@ -9,17 +16,63 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
+	"time"
 )
 
 const (
-	urlKey = "url"
+	urlKey         = "url"
+	historyKey     = "history"
+	snapshotKey    = "snapshot"
+	snapshotTTLKey = "snapshot_ttl"
 )
 
 type config struct {
 	Token   string   `yaml:"token"`
 	URL     string   `yaml:"url"`
 	Players []string `yaml:"players"`
+	// MaxActive is how many of Players run a step at once, accounts set their own
+	MaxActive int `yaml:"max_active" mapstructure:"max_active"`
+	// Accounts are more accounts played in the same process, each with its own token, characters and bank
+	Accounts []engine.Account `yaml:"accounts"`
+	// Listen is the optional address for the local status server, e.g. localhost:8080
+	Listen string `yaml:"listen"`
+	// History is the path of the action history database
+	History string `yaml:"history"`
+	// Objectives tune activity selection per player
+	Objectives map[string]strategy.Objective `yaml:"objectives"`
+	// Tasks controls when tasks are cancelled and task coins exchanged
+	Tasks strategy.TaskPolicy `yaml:"tasks"`
+	// Snapshot is the path of the world snapshot used by offline tools and as the engine's world cache
+	Snapshot string `yaml:"snapshot"`
+	// SnapshotTTL is how old the snapshot may be for the engine to start from it, zero always downloads
+	SnapshotTTL time.Duration `yaml:"snapshot_ttl" mapstructure:"snapshot_ttl"`
+	// Refresh sets how often world data is reloaded from the api
+	Refresh world.RefreshConfig `yaml:"refresh"`
+	// RateLimit sets the request budgets shared by all characters
+	RateLimit ratelimit.Config `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// Record is the optional path every api request and response is written to, see the replay command
+	Record string `yaml:"record"`
+}
+
+// players are the characters of every account
+func (c *config) players() []string {
+	players := slices.Clone(c.Players)
+	for _, a := range c.Accounts {
+		players = append(players, a.Players...)
+	}
+	return players
+}
+
+// token is the token of the account owning the character, the top level token for unknown characters
+func (c *config) token(character string) string {
+	for _, a := range c.Accounts {
+		if slices.Contains(a.Players, character) {
+			return a.Token
+		}
+	}
+	return c.Token
 }
 
 func init() {
@@ -30,6 +83,9 @@ func init() {
 
 	viper.SetConfigFile(dir + "/.artifactsmmo/config.yaml")
 	viper.SetDefault(urlKey, "https://api.artifactsmmo.com")
+	viper.SetDefault(historyKey, dir+"/.artifactsmmo/history.db")
+	viper.SetDefault(snapshotKey, dir+"/.artifactsmmo/world.json")
+	viper.SetDefault(snapshotTTLKey, "24h")
 
 	if err := viper.ReadInConfig(); err != nil {
 		panic(err)
@@ -59,31 +115,77 @@ func main() {
 		exitOnError(err)
 	}
 
-	if cfg.Token == "" {
-		panic(fmt.Errorf("token not found in config"))
+	if len(os.Args) > 1 {
+		go func() {
+			<-sigChan
+			cancel()
+		}()
+		switch os.Args[1] {
+		case "report":
+			exitOnError(runReport(cfg, os.Args[2:]))
+			return
+		case "snapshot":
+			exitOnError(runSnapshot(ctx, cfg, os.Args[2:]))
+			return
+		case "simulate":
+			exitOnError(runSimulate(ctx, cfg, os.Args[2:]))
+			return
+		case "replay":
+			exitOnError(runReplay(ctx, cfg, os.Args[2:]))
+			return
+		default:
+			exitOnError(fmt.Errorf("unknown command %s", os.Args[1]))
+		}
+	}
+
+	if cfg.Token == "" && len(cfg.Accounts) == 0 {
+		panic(fmt.Errorf("token or accounts not found in config"))
+	}
+
+	store, err := history.Open(cfg.History, clock.Real{})
+	exitOnError(err)
+	defer store.Close()
+
+	var recorder *replay.Recorder
+	if cfg.Record != "" {
+		recorder, err = replay.Create(cfg.Record)
+		exitOnError(err)
+		defer recorder.Close()
 	}
 
 	game, err := engine.NewGameEngine(ctx, engine.GameConfig{
 		Token:       cfg.Token,
 		URL:         cfg.URL,
 		PlayerNames: cfg.Players,
+		MaxActive:   cfg.MaxActive,
+		Accounts:    cfg.Accounts,
+		History:     store,
+		Objectives:  cfg.Objectives,
+		TaskPolicy:  cfg.Tasks,
+		Refresh:     cfg.Refresh,
+		Cache:       world.Cache{Path: cfg.Snapshot, TTL: cfg.SnapshotTTL},
+		RateLimit:   cfg.RateLimit,
+		Recorder:    recorder,
 	})
 
 	exitOnError(err)
 
-mainloop:
-	for {
-		select {
-		case <-sigChan:
-			log.Println("signal caught, stopping game")
-			cancel()
-			break mainloop
-		case gErr := <-game.Out:
-			exitOnError(gErr)
-			break mainloop
-		default:
+	var serverErr chan error
+	if cfg.Listen != "" {
+		srv := server.NewServer(cfg.Listen, game)
+		srv.Start(ctx)
+		serverErr = srv.Out
+	}
 
-		}
+	//blocks until one of them fires, serverErr is nil and never fires without a server
+	select {
+	case <-sigChan:
+		log.Println("signal caught, stopping game")
+		cancel()
+	case gErr := <-game.Out:
+		exitOnError(gErr)
+	case sErr := <-serverErr:
+		exitOnError(sErr)
 	}
 	log.Println("game stopped")
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"artifactsmmo/internal/replay"
+	"artifactsmmo/internal/simulate"
+	"context"
+	"flag"
+	"fmt"
+)
+
+// runReplay runs the engine against a recorded session to reproduce the decisions it made
+func runReplay(ctx context.Context, cfg *config, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	path := flags.String("recording", cfg.Record, "recording to replay, written when record is set in the config")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("no recording, set record in the config or pass --recording")
+	}
+
+	rec, err := replay.Load(*path)
+	if err != nil {
+		return err
+	}
+	res, err := simulate.Replay(ctx, simulate.ReplayOptions{
+		Recording:  rec,
+		Objectives: cfg.Objectives,
+		TaskPolicy: cfg.Tasks,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("replayed %d of %d requests\n", res.Served, res.Total)
+	if res.Miss.Diverged() {
+		return fmt.Errorf("replay diverged: %w", res.Miss)
+	}
+	fmt.Printf("recording ended: %s\n", res.Miss)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/history"
+	"artifactsmmo/internal/report"
+	"artifactsmmo/internal/world"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runReport prints xp and gold per hour for every activity in the action history
+func runReport(cfg *config, args []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	since := flags.Duration("since", 7*24*time.Hour, "how far back to report")
+	character := flags.String("character", "", "only report this character")
+	snapshotPath := flags.String("snapshot", cfg.Snapshot, "world snapshot whose prices value the drops, written by the snapshot command")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	snapshot, err := world.LoadSnapshot(*snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	store, err := history.Open(cfg.History, clock.Real{})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	characters := []string{*character}
+	if *character == "" {
+		if characters, err = store.Characters(); err != nil {
+			return fmt.Errorf("list characters: %w", err)
+		}
+	}
+
+	now := time.Now()
+	rows, err := report.Build(store, characters, now.Add(-*since), now, world.PricesOf(snapshot.Items))
+	if err != nil {
+		return err
+	}
+	if err = report.Write(os.Stdout, rows); err != nil {
+		return err
+	}
+
+	rewards, err := report.Rewards(store, characters, now.Add(-*since), now)
+	if err != nil {
+		return err
+	}
+	if len(rewards) == 0 {
+		return nil
+	}
+	fmt.Println()
+	return report.WriteRewards(os.Stdout, rewards)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"artifactsmmo/internal/clock"
+	"artifactsmmo/internal/fake"
+	"artifactsmmo/internal/simulate"
+	"artifactsmmo/internal/world"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/promiseofcake/artifactsmmo-go-client/client"
+	"github.com/sagikazarmark/slog-shim"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runSimulate plays the configured characters against a local copy of the world
+func runSimulate(ctx context.Context, cfg *config, args []string) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	hours := flags.Float64("hours", 24, "game hours every character plays")
+	snapshotPath := flags.String("snapshot", cfg.Snapshot, "world snapshot to simulate, written by the snapshot command")
+	characters := flags.String("characters", strings.Join(cfg.players(), ","), "comma separated characters to simulate")
+	fresh := flags.Bool("fresh", false, "start from new level 1 characters instead of the current ones")
+	seed := flags.Int64("seed", 1, "seed for drops")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	//the engine logs every action, only keep what went wrong
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	snapshot, err := world.LoadSnapshot(*snapshotPath)
+	if err != nil {
+		return err
+	}
+
+	names := strings.Split(*characters, ",")
+	start := make([]client.CharacterSchema, 0, len(names))
+	for _, n := range names {
+		if *fresh {
+			start = append(start, fake.NewCharacter(n))
+			continue
+		}
+		c, err := getCharacter(ctx, cfg, n)
+		if err != nil {
+			return fmt.Errorf("%w, use --fresh to simulate new characters", err)
+		}
+		start = append(start, c)
+	}
+
+	results, err := simulate.Run(ctx, simulate.Options{
+		Snapshot:   snapshot,
+		Characters: start,
+		Duration:   time.Duration(*hours * float64(time.Hour)),
+		Objectives: cfg.Objectives,
+		TaskPolicy: cfg.Tasks,
+		Seed:       *seed,
+	})
+	if err != nil {
+		return err
+	}
+	return simulate.Write(os.Stdout, results)
+}
+
+// runSnapshot saves the static world data for offline tools
+func runSnapshot(ctx context.Context, cfg *config, args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	out := flags.String("out", cfg.Snapshot, "file to write")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	c, err := newClient(cfg.URL, cfg.Token)
+	if err != nil {
+		return err
+	}
+	snapshot, err := world.DownloadSnapshot(ctx, c, clock.Real{})
+	if err != nil {
+		return err
+	}
+	if err = snapshot.Save(*out); err != nil {
+		return err
+	}
+	fmt.Printf("saved %d tiles, %d monsters, %d resources and %d items to %s\n",
+		len(snapshot.Tiles), len(snapshot.Monsters), len(snapshot.Resources), len(snapshot.Items), *out)
+	return nil
+}
+
+func getCharacter(ctx context.Context, cfg *config, name string) (client.CharacterSchema, error) {
+	token := cfg.token(name)
+	if token == "" {
+		return client.CharacterSchema{}, fmt.Errorf("token of %s not found in config", name)
+	}
+	c, err := newClient(cfg.URL, token)
+	if err != nil {
+		return client.CharacterSchema{}, err
+	}
+	resp, err := c.GetCharacterCharactersNameGetWithResponse(ctx, name)
+	if err != nil {
+		return client.CharacterSchema{}, fmt.Errorf("get character %s: %w", name, err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return client.CharacterSchema{}, fmt.Errorf("get character %s: %d", name, resp.StatusCode())
+	}
+	return resp.JSON200.Data, nil
+}
+
+// newClient is a plain client for one off commands, the engine builds its own with retries
+func newClient(url, token string) (*client.ClientWithResponses, error) {
+	opts := make([]client.ClientOption, 0)
+	if token != "" {
+		opts = append(opts, client.WithRequestEditorFn(client.NewBearerAuthorizationRequestFunc(token)))
+	}
+	c, err := client.NewClientWithResponses(url, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client: %w", err)
+	}
+	return c, nil
+}